@@ -0,0 +1,2096 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"html/template"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/c4s4/gointerfaces"
+)
+
+// printImplementers prints types implementing an interface
+func printImplementers(w io.Writer, implementers []gointerfaces.Implementer) {
+	lines := make([][]string, 0, len(implementers))
+	for _, implementer := range implementers {
+		name := implementer.Name
+		if implementer.Pointer {
+			name = "*" + name
+		}
+		lines = append(lines, []string{name, implementer.Package, implementer.Link})
+	}
+	printTable(w, []string{"Type", "Package", "Source"}, lines)
+}
+
+// printInterfaces prints interfaces for given versions in given order, with
+// their methods and type set of constraints beneath if methods is true, and
+// a package column unless printed in a heading. This aligned table is meant
+// for terminals and thus prints no links: version columns give the line of
+// declaration, prefixed with the file if not the one of the File column.
+func printInterfaces(w io.Writer, interfaceList gointerfaces.InterfaceList, versions []string, order string, methods, packageColumn bool, style tableStyle) {
+	header := []string{"Interface", "Package", "File", "Methods"}
+	header = append(header, versions...)
+	lines := make([][]string, 0)
+	beneath := make([][]string, 0)
+	for _, i := range interfaceList.Sorted(versions, order) {
+		latest := interfaceList.Latest(i, versions)
+		line := []string{i.Name + latest.TypeParams, i.Package, latest.SourceFile, strconv.Itoa(latest.MethodCount)}
+		for _, v := range versions {
+			location, ok := interfaceList[i][v]
+			switch {
+			case !ok:
+				line = append(line, "-")
+			case location.SourceFile != latest.SourceFile:
+				line = append(line, location.SourceFile+":"+location.LineNumber)
+			default:
+				line = append(line, location.LineNumber)
+			}
+		}
+		lines = append(lines, line)
+		extra := make([]string, 0)
+		if latest.Doc != "" {
+			for _, line := range strings.Split(latest.Doc, "\n") {
+				extra = append(extra, strings.TrimRight("    // "+line, " "))
+			}
+		}
+		if methods {
+			for _, method := range latest.Methods {
+				extra = append(extra, "    "+method.String())
+			}
+			// type set elements of constraints are printed as a whole
+			if latest.Constraint != "" {
+				extra = append(extra, "    constraint "+latest.Constraint)
+			}
+		}
+		if latest.Alias {
+			extra = append(extra, "    alias of "+latest.Target)
+		}
+		for _, alternate := range latest.Alternates {
+			extra = append(extra, "    also in "+alternate.SourceFile+":"+alternate.LineNumber)
+		}
+		beneath = append(beneath, extra)
+	}
+	// names, packages and lines of versions are colored
+	colors := []string{colorName, colorPackage, "", ""}
+	for range versions {
+		colors = append(colors, colorDim)
+	}
+	if !packageColumn {
+		header, colors = removeColumn(header, 1), removeColumn(colors, 1)
+		for l := range lines {
+			lines[l] = removeColumn(lines[l], 1)
+		}
+	}
+	printAligned(w, header, lines, beneath, colors, style)
+}
+
+// removeColumn returns cells without the one at index
+func removeColumn(cells []string, index int) []string {
+	return append(cells[:index:index], cells[index+1:]...)
+}
+
+// packageGroups calls print with interfaces of each package declaring some
+// in versions, sorted by package, after a heading printed by heading
+func packageGroups(w io.Writer, interfaceList gointerfaces.InterfaceList, versions []string, heading string, print func(gointerfaces.InterfaceList)) {
+	for p, pkg := range interfaceList.Packages(versions) {
+		if p > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, heading, pkg.Package)
+		print(interfaceList.Filter(func(interf gointerfaces.Interface, location gointerfaces.Location) bool {
+			return interf.Package == pkg.Package
+		}))
+	}
+}
+
+// Alignments of columns in table format
+const (
+	alignAuto  = "auto"
+	alignLeft  = "left"
+	alignRight = "right"
+)
+
+// tableStyle is the layout of tables in table format
+type tableStyle struct {
+	// separator is printed between columns
+	separator string
+	// align is the alignment of all columns, numbers are right aligned and
+	// text left aligned with alignAuto
+	align string
+	// header tells if header and separator lines are printed
+	header bool
+	// color tells if cells are printed with terminal colors
+	color bool
+}
+
+// Terminal colors of table cells
+const (
+	colorName    = "\033[36m"
+	colorPackage = "\033[35m"
+	colorDim     = "\033[2m"
+	colorReset   = "\033[0m"
+)
+
+// Modes of color output
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// fieldColors are terminal colors of fields in tables
+var fieldColors = map[string]string{
+	"name":    colorName,
+	"package": colorPackage,
+	"line":    colorDim,
+}
+
+// numberRegexp matches cells of numeric columns, with - for missing values
+var numberRegexp = regexp.MustCompile(`^(\d+|-)$`)
+
+// rightAligned tells if column c of lines is right aligned in given style
+func rightAligned(lines [][]string, c int, align string) bool {
+	if align != alignAuto {
+		return align == alignRight
+	}
+	for _, line := range lines {
+		if !numberRegexp.MatchString(line[c]) {
+			return false
+		}
+	}
+	return len(lines) > 0
+}
+
+// printAligned prints a table with given header and lines in given style,
+// with lines of beneath, if any, printed under each line. If style is
+// colored, cells of lines are printed with terminal colors of their column,
+// none if empty.
+func printAligned(w io.Writer, header []string, lines, beneath [][]string, colors []string, style tableStyle) {
+	widths := make([]int, len(header))
+	right := make([]bool, len(header))
+	for _, line := range append([][]string{header}, lines...) {
+		for c, cell := range line {
+			if len(cell) > widths[c] {
+				widths[c] = len(cell)
+			}
+		}
+	}
+	separator := ""
+	for c, width := range widths {
+		if c > 0 {
+			separator += style.separator
+		}
+		right[c] = rightAligned(lines, c, style.align)
+		if right[c] {
+			separator += strings.Repeat("-", width-1) + ":"
+		} else {
+			separator += ":" + strings.Repeat("-", width-1)
+		}
+	}
+	// colors are outside of padding, so that they do not count in width
+	printLine := func(line []string, colored bool) {
+		cells := make([]string, len(line))
+		for c, cell := range line {
+			padding := strings.Repeat(" ", widths[c]-len(cell))
+			if colored && c < len(colors) && colors[c] != "" && cell != "" {
+				cell = colors[c] + cell + colorReset
+			}
+			if right[c] {
+				cells[c] = padding + cell
+			} else {
+				cells[c] = cell + padding
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, style.separator), " "))
+	}
+	if style.header {
+		printLine(header, false)
+		fmt.Fprintln(w, strings.TrimRight(separator, " "))
+	}
+	for l, line := range lines {
+		printLine(line, style.color)
+		if l < len(beneath) {
+			for _, extra := range beneath[l] {
+				fmt.Fprintln(w, extra)
+			}
+		}
+	}
+}
+
+// printSummary prints total number of interfaces and number per package
+func printSummary(w io.Writer, interfaces []gointerfaces.Interface) {
+	fmt.Fprintf(w, "\nTotal: %d interfaces\n\n", len(interfaces))
+	lines := make([][]string, 0)
+	for _, count := range gointerfaces.CountByPackage(interfaces) {
+		lines = append(lines, []string{count.Package, strconv.Itoa(count.Count)})
+	}
+	printTable(w, []string{"Package", "Interfaces"}, lines)
+}
+
+// printPackages prints a package per line or, if counted, a table of
+// number of interfaces per package
+func printPackages(w io.Writer, packages []gointerfaces.PackageCount, counted bool) {
+	if !counted {
+		for _, pkg := range packages {
+			fmt.Fprintln(w, pkg.Package)
+		}
+		return
+	}
+	lines := make([][]string, 0, len(packages))
+	for _, pkg := range packages {
+		lines = append(lines, []string{pkg.Package, strconv.Itoa(pkg.Count)})
+	}
+	printTable(w, []string{"Package", "Interfaces"}, lines)
+}
+
+// printCounts prints number of interfaces per version and, if counted, a
+// table of number per package with a column per version
+func printCounts(w io.Writer, counts []gointerfaces.VersionCount) {
+	lines := make([][]string, 0, len(counts))
+	for _, count := range counts {
+		lines = append(lines, []string{count.Version, strconv.Itoa(count.Total)})
+	}
+	printTable(w, []string{"Version", "Interfaces"}, lines)
+	header := []string{"Package"}
+	perPackage := make(map[string][]string)
+	packages := make([]string, 0)
+	for v, count := range counts {
+		header = append(header, count.Version)
+		for _, packageCount := range count.Packages {
+			if perPackage[packageCount.Package] == nil {
+				perPackage[packageCount.Package] = make([]string, len(counts))
+				packages = append(packages, packageCount.Package)
+			}
+			perPackage[packageCount.Package][v] = strconv.Itoa(packageCount.Count)
+		}
+	}
+	if len(packages) == 0 {
+		return
+	}
+	sort.Strings(packages)
+	lines = make([][]string, 0, len(packages))
+	for _, pkg := range packages {
+		line := []string{pkg}
+		for _, count := range perPackage[pkg] {
+			if count == "" {
+				count = "0"
+			}
+			line = append(line, count)
+		}
+		lines = append(lines, line)
+	}
+	fmt.Fprintln(w)
+	printTable(w, header, lines)
+}
+
+// stringList is a command line flag that may be repeated
+type stringList []string
+
+// String returns values of the flag separated with commas
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set adds a value to the flag
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// result is the result of interface extraction for a version
+type result struct {
+	index      int
+	version    string
+	interfaces map[gointerfaces.Interface]gointerfaces.Location
+	err        error
+}
+
+// extractVersions extracts interfaces for given versions with a pool of
+// jobs workers, results are returned in the order of versions. If failFast
+// is true, versions are skipped after the first error. If emit is not nil,
+// it is called with each result as soon as results of previous versions are
+// available, so that they are emitted in the order of versions.
+func extractVersions(ctx context.Context, extractor *gointerfaces.Extractor, versions []string, jobs int, failFast bool, emit func(result)) []result {
+	if len(versions) == 0 {
+		return nil
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	indexes := make(chan int)
+	results := make(chan result)
+	for w := 0; w < jobs; w++ {
+		go func() {
+			for index := range indexes {
+				if err := ctx.Err(); err != nil {
+					results <- result{index: index, version: versions[index], err: err}
+					continue
+				}
+				interfaces, err := extractor.InterfacesForVersion(ctx, versions[index])
+				if err != nil && failFast {
+					cancel()
+				}
+				results <- result{index: index, version: versions[index], interfaces: interfaces, err: err}
+			}
+		}()
+	}
+	go func() {
+		for index := range versions {
+			indexes <- index
+		}
+		close(indexes)
+	}()
+	ordered := make([]result, len(versions))
+	received := make([]bool, len(versions))
+	next := 0
+	for done := 1; done <= len(versions); done++ {
+		r := <-results
+		ordered[r.index] = r
+		received[r.index] = true
+		if r.err != nil {
+			extractor.Logger.Statusf("[%d/%d] go%s — failed", done, len(versions), r.version)
+		} else {
+			extractor.Logger.Statusf("[%d/%d] go%s — %d files, %d interfaces", done, len(versions), r.version, extractor.ParsedFiles(r.version), len(r.interfaces))
+		}
+		// results received out of order wait for previous versions
+		for emit != nil && next < len(versions) && received[next] {
+			extractor.Logger.ClearStatus()
+			emit(ordered[next])
+			next++
+		}
+	}
+	extractor.Logger.ClearStatus()
+	return ordered
+}
+
+// aggregate merges interfaces of results in a list and returns it with
+// their versions, in order. Results in error are skipped.
+func aggregate(results []result) (gointerfaces.InterfaceList, []string) {
+	interfaces := gointerfaces.NewInterfaceList()
+	versions := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		interfaces.AddInterfaces(result.version, result.interfaces)
+		versions = append(versions, result.version)
+	}
+	return interfaces, versions
+}
+
+// reportStatus logs status of each result, with number of source files
+// skipped, and returns the number of results in error. Versions skipped
+// after a failure are not counted.
+func reportStatus(results []result, extractor *gointerfaces.Extractor) int {
+	logger := extractor.Logger
+	failures := 0
+	for _, result := range results {
+		switch {
+		case result.err == nil:
+			skipped := extractor.FileErrors(result.version)
+			fields := gointerfaces.Fields{"version": result.version, "count": len(result.interfaces)}
+			if len(skipped) == 0 {
+				logger.Logf(gointerfaces.LevelInfo, fields, "%s: ok, %d interfaces", result.version, len(result.interfaces))
+				break
+			}
+			fields["skipped"] = len(skipped)
+			logger.Logf(gointerfaces.LevelError, fields, "%s: ok, %d interfaces, %d files skipped", result.version, len(result.interfaces), len(skipped))
+			for _, fileError := range skipped {
+				logger.Logf(gointerfaces.LevelDebug, gointerfaces.Fields{"version": result.version, "file": fileError.Path, "error": fileError.Err},
+					"%s: %v", result.version, fileError)
+			}
+		case errors.Is(result.err, context.Canceled):
+			logger.Logf(gointerfaces.LevelError, gointerfaces.Fields{"version": result.version}, "%s: skipped", result.version)
+		default:
+			logger.Logf(gointerfaces.LevelError, gointerfaces.Fields{"version": result.version, "error": result.err}, "%s: failed: %v", result.version, result.err)
+			failures++
+		}
+	}
+	return failures
+}
+
+// printTable prints an aligned table with given header and lines
+func printTable(w io.Writer, header []string, lines [][]string) {
+	widths := make([]int, len(header))
+	for _, line := range append([][]string{header}, lines...) {
+		for i, cell := range line {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	formatLine := ""
+	separator := ""
+	for i, width := range widths {
+		if i > 0 {
+			formatLine += " | "
+			separator += " | "
+		}
+		formatLine += "%-" + strconv.Itoa(width) + "s"
+		separator += strings.Repeat("-", width)
+	}
+	for i, line := range append([][]string{header}, lines...) {
+		args := make([]interface{}, len(line))
+		for j, cell := range line {
+			args[j] = cell
+		}
+		fmt.Fprintln(w, strings.TrimRight(fmt.Sprintf(formatLine, args...), " "))
+		if i == 0 {
+			fmt.Fprintln(w, separator)
+		}
+	}
+}
+
+// printDiff prints the difference between two versions in sections
+func printDiff(w io.Writer, diff gointerfaces.Diff) {
+	fmt.Fprintf(w, "Added in %s\n\n", diff.To)
+	lines := make([][]string, 0)
+	for _, row := range diff.Added {
+		lines = append(lines, []string{row.Name, row.Package, row.Link})
+	}
+	printTable(w, []string{"Interface", "Package", "Source"}, lines)
+	fmt.Fprintf(w, "\nRemoved in %s\n\n", diff.To)
+	lines = make([][]string, 0)
+	for _, row := range diff.Removed {
+		lines = append(lines, []string{row.Name, row.Package, row.Link})
+	}
+	printTable(w, []string{"Interface", "Package", "Source"}, lines)
+	fmt.Fprintf(w, "\nMoved in %s\n\n", diff.To)
+	lines = make([][]string, 0)
+	for _, move := range diff.Moved {
+		lines = append(lines, []string{move.Name, move.Package, move.From.Link, move.To.Link})
+	}
+	printTable(w, []string{"Interface", "Package", diff.From, diff.To}, lines)
+}
+
+// printPlans prints archives which would be downloaded for versions, and
+// tarball if any
+func printPlans(w io.Writer, plans []gointerfaces.Plan, tarball string) {
+	lines := make([][]string, 0, len(plans)+1)
+	for _, plan := range plans {
+		archive := "download"
+		switch {
+		case plan.CachePath == "":
+			archive = "download, no cache"
+		case plan.Cached:
+			archive = "cached in " + plan.CachePath
+		}
+		interfaces := "parse"
+		if plan.ResultCached {
+			interfaces = "cached"
+		}
+		lines = append(lines, []string{plan.Version, plan.URL, archive, interfaces, plan.Links})
+	}
+	if tarball != "" {
+		lines = append(lines, []string{"-", tarball, "download, no cache", "parse", "-"})
+	}
+	printTable(w, []string{"Version", "Archive", "Status", "Interfaces", "Links"}, lines)
+}
+
+// printDuplicates prints groups of interfaces with the same method set,
+// with their methods and a table of interfaces
+func printDuplicates(w io.Writer, duplicates []gointerfaces.Duplicate) {
+	for d, duplicate := range duplicates {
+		if d > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Method set %s\n\n", duplicate.Hash)
+		for _, method := range duplicate.Methods {
+			fmt.Fprintf(w, "    %s\n", method)
+		}
+		fmt.Fprintln(w)
+		lines := make([][]string, 0, len(duplicate.Interfaces))
+		for _, row := range duplicate.Interfaces {
+			lines = append(lines, []string{row.Name, row.Package, row.SourceFile + ":" + row.LineNumber, row.Version})
+		}
+		printTable(w, []string{"Interface", "Package", "File", "Version"}, lines)
+	}
+}
+
+// printComparison prints interfaces only in each package and names of those
+// in both
+func printComparison(w io.Writer, comparison gointerfaces.PackageComparison) {
+	for p, only := range [][]gointerfaces.Row{comparison.OnlyFirst, comparison.OnlySecond} {
+		pkg := comparison.First
+		if p > 0 {
+			pkg = comparison.Second
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Only in %s\n\n", pkg)
+		lines := make([][]string, 0, len(only))
+		for _, row := range only {
+			lines = append(lines, []string{row.Name, row.SourceFile + ":" + row.LineNumber, row.Link})
+		}
+		printTable(w, []string{"Interface", "File", "Source"}, lines)
+	}
+	fmt.Fprintf(w, "\nIn both %s and %s\n\n", comparison.First, comparison.Second)
+	lines := make([][]string, 0, len(comparison.Both))
+	for _, name := range comparison.Both {
+		lines = append(lines, []string{name})
+	}
+	printTable(w, []string{"Interface"}, lines)
+}
+
+// printAdditions prints interfaces added in a version since a previous one
+func printAdditions(w io.Writer, diff gointerfaces.Diff) {
+	fmt.Fprintf(w, "New in %s since %s\n\n", diff.To, diff.From)
+	lines := make([][]string, 0, len(diff.Added))
+	for _, row := range diff.Added {
+		lines = append(lines, []string{row.Name, row.Package, row.SourceFile + ":" + row.LineNumber, row.Link})
+	}
+	printTable(w, []string{"Interface", "Package", "File", "Source"}, lines)
+}
+
+// printMethodDiff prints interfaces which methods changed, with added and
+// removed methods beneath
+func printMethodDiff(w io.Writer, from, to string, changes []gointerfaces.MethodChange) {
+	fmt.Fprintf(w, "Methods changed from %s to %s\n", from, to)
+	for _, change := range changes {
+		fmt.Fprintf(w, "\n%s.%s\n", change.Package, change.Name)
+		for _, method := range change.Added {
+			fmt.Fprintln(w, "    +"+method.String())
+		}
+		for _, method := range change.Removed {
+			fmt.Fprintln(w, "    -"+method.String())
+		}
+	}
+}
+
+// printIntroductions prints the version introducing each interface
+func printIntroductions(w io.Writer, introductions []gointerfaces.Introduction) {
+	lines := make([][]string, 0, len(introductions))
+	dated := false
+	for _, introduction := range introductions {
+		dated = dated || introduction.ReleaseDate != ""
+	}
+	for _, introduction := range introductions {
+		line := []string{introduction.Name, introduction.Package, introduction.IntroducedIn}
+		if dated {
+			line = append(line, introduction.ReleaseDate)
+		}
+		lines = append(lines, append(line, introduction.Link))
+	}
+	header := []string{"Interface", "Package", "IntroducedIn"}
+	if dated {
+		header = append(header, "ReleaseDate")
+	}
+	printTable(w, append(header, "Source"), lines)
+}
+
+// field is a column of rows selectable with -fields
+type field struct {
+	header string
+	value  func(row gointerfaces.Row) string
+}
+
+// fields are the columns selectable with -fields, by name
+var fields = map[string]field{
+	"name":    {"Interface", func(row gointerfaces.Row) string { return row.Name }},
+	"package": {"Package", func(row gointerfaces.Row) string { return row.Package }},
+	"file":    {"SourceFile", func(row gointerfaces.Row) string { return row.SourceFile }},
+	"line":    {"Line", func(row gointerfaces.Row) string { return row.LineNumber }},
+	"link":    {"Link", func(row gointerfaces.Row) string { return row.Link }},
+	"version": {"Version", func(row gointerfaces.Row) string { return row.Version }},
+	"date":    {"ReleaseDate", func(row gointerfaces.Row) string { return row.ReleaseDate }},
+	"methods": {"Methods", func(row gointerfaces.Row) string {
+		methods := make([]string, 0, len(row.Methods))
+		for _, method := range row.Methods {
+			methods = append(methods, method.String())
+		}
+		return strings.Join(methods, "; ")
+	}},
+	"tags":  {"BuildTags", func(row gointerfaces.Row) string { return row.BuildTags }},
+	"count": {"MethodCount", func(row gointerfaces.Row) string { return strconv.Itoa(row.MethodCount) }},
+	"doc":   {"Doc", func(row gointerfaces.Row) string { return strings.Join(strings.Fields(row.Doc), " ") }},
+}
+
+// csvFields returns the fields of CSV format, unless selected with -fields,
+// with a version column only if several versions are printed
+func csvFields(versions []string) []string {
+	if len(versions) > 1 {
+		return []string{"name", "package", "version", "file", "line", "link"}
+	}
+	return []string{"name", "package", "file", "line", "link"}
+}
+
+// parseFields parses a comma separated list of field names
+func parseFields(value string) ([]string, error) {
+	names := strings.Split(value, ",")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if _, ok := fields[names[i]]; !ok {
+			return nil, fmt.Errorf("unknown field %q", names[i])
+		}
+	}
+	return names, nil
+}
+
+// fieldLines sorts rows in given order and returns header and lines with
+// given fields
+func fieldLines(rows []gointerfaces.Row, order string, names []string) ([]string, [][]string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return gointerfaces.Less(order, rows[i].Interface, rows[i].Location, rows[j].Interface, rows[j].Location)
+	})
+	header := make([]string, len(names))
+	for i, name := range names {
+		header[i] = fields[name].header
+	}
+	lines := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		line := make([]string, len(names))
+		for i, name := range names {
+			line[i] = fields[name].value(row)
+		}
+		lines = append(lines, line)
+	}
+	return header, lines
+}
+
+// printCompact prints an interface per line, as package.Name and file:line
+// of its latest declaration separated by a tab, without header
+func printCompact(w io.Writer, interfaceList gointerfaces.InterfaceList, versions []string, order string) {
+	for _, i := range interfaceList.Sorted(versions, order) {
+		latest := interfaceList.Latest(i, versions)
+		fmt.Fprintf(w, "%s\t%s:%s\n", i.QualifiedName(), latest.SourceFile, latest.LineNumber)
+	}
+}
+
+// printCSV prints rows in CSV format with given fields and separator, such
+// as a comma or a tab, sorted in given order
+func printCSV(w io.Writer, rows []gointerfaces.Row, order string, names []string, separator rune) error {
+	header, lines := fieldLines(rows, order, names)
+	writer := csv.NewWriter(w)
+	writer.Comma = separator
+	writer.Write(header)
+	writer.WriteAll(lines)
+	return writer.Error()
+}
+
+// markdownEscaper escapes characters breaking Markdown table cells
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\n", " ")
+
+// printMarkdown prints interfaces as a GitHub flavored Markdown table, with
+// a column per version linking to sources
+func printMarkdown(w io.Writer, interfaceList gointerfaces.InterfaceList, versions []string, order string, packageColumn bool) {
+	header := "| Interface | Package | File | Line | Methods |"
+	separator := "| --- | --- | --- | ---: | ---: |"
+	if !packageColumn {
+		header = "| Interface | File | Line | Methods |"
+		separator = "| --- | --- | ---: | ---: |"
+	}
+	for _, v := range versions {
+		header += " " + markdownEscaper.Replace(v) + " |"
+		separator += " --- |"
+	}
+	fmt.Fprintln(w, header)
+	fmt.Fprintln(w, separator)
+	for _, i := range interfaceList.Sorted(versions, order) {
+		latest := interfaceList.Latest(i, versions)
+		cells := []string{i.Name + latest.TypeParams, i.Package, latest.SourceFile, latest.LineNumber, strconv.Itoa(latest.MethodCount)}
+		for k, cell := range cells {
+			cells[k] = markdownEscaper.Replace(cell)
+		}
+		if latest.Doc != "" {
+			// paragraphs of doc comment are separated by line breaks
+			cells[0] += "<br>" + markdownEscaper.Replace(strings.ReplaceAll(latest.Doc, "\n\n", "<br>"))
+		}
+		if !packageColumn {
+			cells = removeColumn(cells, 1)
+		}
+		for _, v := range versions {
+			if location := interfaceList[i][v]; location.Link != "" {
+				cells = append(cells, "[source]("+location.Link+")")
+			} else if location.SourceFile != "" {
+				// no link for tarballs without -source-url
+				cells = append(cells, "source")
+			} else {
+				cells = append(cells, "-")
+			}
+		}
+		fmt.Fprintln(w, "| "+strings.Join(cells, " | ")+" |")
+	}
+}
+
+// printMarkdownTable prints a GitHub flavored Markdown table with given
+// header and lines
+func printMarkdownTable(w io.Writer, header []string, lines [][]string) {
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	for _, line := range append([][]string{header, separator}, lines...) {
+		cells := make([]string, len(line))
+		for i, cell := range line {
+			cells[i] = markdownEscaper.Replace(cell)
+		}
+		fmt.Fprintln(w, "| "+strings.Join(cells, " | ")+" |")
+	}
+}
+
+// htmlTemplate is the template of HTML report
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GO Interfaces</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { cursor: pointer; background: #eee; }
+.doc { color: #555; font-size: smaller; white-space: pre-line; max-width: 40em; }
+</style>
+</head>
+<body>
+<h1>GO Interfaces</h1>
+<p>Versions: {{range $i, $v := .Versions}}{{if $i}}, {{end}}{{$v}}{{end}}</p>
+<p>Generated: {{.Generated}}</p>
+<table id="interfaces">
+<thead>
+<tr><th>Interface</th><th>Package</th><th>Methods</th><th>Embeds</th>{{range .Versions}}<th>{{.}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{range .Interfaces}}<tr><td><a href="{{.Link}}">{{.Name}}</a>{{if .Doc}}<div class="doc">{{.Doc}}</div>{{end}}</td><td>{{.Package}}</td><td>{{.MethodCount}}</td><td>{{range $i, $e := .Embeds}}{{if $i}}, {{end}}{{if $e.Link}}<a href="{{$e.Link}}">{{$e.Name}}</a>{{else}}{{$e.Name}}{{end}}{{end}}</td>{{range .Links}}<td>{{if .}}<a href="{{.}}">source</a>{{else}}-{{end}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#interfaces th").forEach(function(th, column) {
+  th.addEventListener("click", function() {
+    var body = document.querySelector("#interfaces tbody");
+    var rows = Array.from(body.rows);
+    var ascending = th.dataset.order !== "asc";
+    th.dataset.order = ascending ? "asc" : "desc";
+    rows.sort(function(a, b) {
+      var x = a.cells[column].textContent, y = b.cells[column].textContent;
+      var c = isNaN(x) || isNaN(y) ? x.localeCompare(y) : x - y;
+      return ascending ? c : -c;
+    });
+    rows.forEach(function(row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`
+
+// htmlInterface is an interface in HTML report
+type htmlInterface struct {
+	gointerfaces.Interface
+	Link        string
+	Doc         string
+	MethodCount int
+	Embeds      []htmlEmbed
+	Links       []string
+}
+
+// htmlEmbed is an embedded interface in HTML report, with link to its
+// definition if known
+type htmlEmbed struct {
+	Name string
+	Link string
+}
+
+// printHTML prints interfaces for given versions as a standalone HTML page,
+// sorted in given order
+func printHTML(w io.Writer, interfaceList gointerfaces.InterfaceList, versions []string, order string) error {
+	interfaces := interfaceList.Sorted(versions, order)
+	data := struct {
+		Versions   []string
+		Generated  string
+		Interfaces []htmlInterface
+	}{
+		Versions:  versions,
+		Generated: time.Now().Format(time.RFC3339),
+	}
+	for _, i := range interfaces {
+		latest := interfaceList.Latest(i, versions)
+		row := htmlInterface{Interface: i, Link: latest.Link, Doc: latest.Doc, MethodCount: latest.MethodCount}
+		for _, embed := range latest.Embeds {
+			row.Embeds = append(row.Embeds, htmlEmbed{Name: embed, Link: latest.EmbedLinks[embed]})
+		}
+		for _, v := range versions {
+			row.Links = append(row.Links, interfaceList[i][v].Link)
+		}
+		data.Interfaces = append(data.Interfaces, row)
+	}
+	return template.Must(template.New("html").Parse(htmlTemplate)).Execute(w, data)
+}
+
+// printJSON prints a value as indented JSON
+func printJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// readSnapshot reads interfaces of the latest version of a JSON snapshot
+// file, and returns them with this version
+func readSnapshot(path string) (map[gointerfaces.Interface]gointerfaces.Location, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+	list, versions, err := gointerfaces.ReadSnapshot(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %v", path, err)
+	}
+	if len(versions) == 0 {
+		return nil, "", fmt.Errorf("%s: no interface in snapshot", path)
+	}
+	version := versions[len(versions)-1]
+	interfaces := make(map[gointerfaces.Interface]gointerfaces.Location)
+	for interf, locations := range list {
+		if location, ok := locations[version]; ok {
+			interfaces[interf] = location
+		}
+	}
+	return interfaces, version, nil
+}
+
+// readVersions reads versions one per line, ignoring blank lines and
+// comments starting with #
+func readVersions(reader io.Reader) ([]string, error) {
+	versions := make([]string, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		if version := strings.TrimSpace(line); version != "" {
+			versions = append(versions, version)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read versions: %v", err)
+	}
+	return versions, nil
+}
+
+// readVersionsFile reads versions in a file, one per line
+func readVersionsFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readVersions(file)
+}
+
+// uniqueVersions returns versions without duplicates, in order of first
+// occurrence
+func uniqueVersions(versions []string, logger *gointerfaces.Logger) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(versions))
+	for _, version := range versions {
+		if seen[version] {
+			logger.Debugf("Ignoring duplicate version %s", version)
+			continue
+		}
+		seen[version] = true
+		unique = append(unique, version)
+	}
+	return unique
+}
+
+// printGo prints a GO source file declaring a map of latest declarations of
+// interfaces, by package.Name, in given package
+func printGo(w io.Writer, interfaceList gointerfaces.InterfaceList, versions []string, pkg string) error {
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "// Code generated by gointerfaces; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n\n", pkg)
+	fmt.Fprintf(&source, "// Location is the latest declaration of an interface\n")
+	fmt.Fprintf(&source, "type Location struct {\nVersion string\nSourceFile string\nLine int\nLink string\n}\n\n")
+	fmt.Fprintf(&source, "// Interfaces are locations of interfaces by package.Name\n")
+	fmt.Fprintf(&source, "var Interfaces = map[string]Location{\n")
+	for _, i := range interfaceList.Sorted(versions, gointerfaces.SortPackage) {
+		version := interfaceList.LatestVersion(i, versions)
+		location := interfaceList[i][version]
+		line, _ := strconv.Atoi(location.LineNumber)
+		fmt.Fprintf(&source, "%q: {Version: %q, SourceFile: %q, Line: %d, Link: %q},\n",
+			i.QualifiedName(), version, location.SourceFile, line, location.Link)
+	}
+	fmt.Fprintf(&source, "}\n")
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not format GO source: %v", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// templateFuncs are functions available in templates of template format
+var templateFuncs = texttemplate.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"markdown": markdownEscaper.Replace,
+	"html":     template.HTMLEscapeString,
+	"csv": func(value string) string {
+		if strings.ContainsAny(value, "\",\n") {
+			return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+		}
+		return value
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// parseTemplate parses a text template in a file, with template functions
+func parseTemplate(path string) (*texttemplate.Template, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read template: %v", err)
+	}
+	tmpl, err := texttemplate.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// printTemplate prints rows with a template, which is passed versions and
+// rows
+func printTemplate(w io.Writer, tmpl *texttemplate.Template, versions []string, rows []gointerfaces.Row) error {
+	data := struct {
+		Versions []string
+		Rows     []gointerfaces.Row
+	}{versions, rows}
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("could not execute template: %v", err)
+	}
+	return nil
+}
+
+// printJSONLines prints rows as JSON objects, one per line, written as they
+// are encoded
+func printJSONLines(w io.Writer, rows []gointerfaces.Row) error {
+	encoder := json.NewEncoder(w)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xmlInterfaces is the root element of XML format
+type xmlInterfaces struct {
+	XMLName    xml.Name       `xml:"interfaces"`
+	Interfaces []xmlInterface `xml:"interface"`
+}
+
+// xmlInterface is an interface in a version in XML format
+type xmlInterface struct {
+	Name    string `xml:"name,attr"`
+	Package string `xml:"package,attr"`
+	Version string `xml:"version,attr"`
+	File    string `xml:"file,attr"`
+	Line    string `xml:"line,attr"`
+	Link    string `xml:"link,attr"`
+}
+
+// printXML prints rows sorted in given order as interface elements of an
+// interfaces root element
+func printXML(w io.Writer, rows []gointerfaces.Row, order string) error {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return gointerfaces.Less(order, rows[i].Interface, rows[i].Location, rows[j].Interface, rows[j].Location)
+	})
+	root := xmlInterfaces{Interfaces: make([]xmlInterface, 0, len(rows))}
+	for _, row := range rows {
+		root.Interfaces = append(root.Interfaces, xmlInterface{
+			Name:    row.Name,
+			Package: row.Package,
+			Version: row.Version,
+			File:    row.SourceFile,
+			Line:    row.LineNumber,
+			Link:    row.Link,
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// goVersion returns the version of the go command in path, such as 1.21.5
+func goVersion() (string, error) {
+	output, err := exec.Command("go", "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not get go version: %v", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) < 3 {
+		return "", fmt.Errorf("could not get go version: unexpected output %q", output)
+	}
+	return strings.TrimPrefix(fields[2], "go"), nil
+}
+
+// indexCacheFile is the file, in cache directory, caching the version index
+const indexCacheFile = "version-index.json"
+
+// watchStateFile is the file, in cache directory or in watch directory
+// without cache, holding the last version written in watch mode
+const watchStateFile = "watch-state"
+
+// watchReleases polls the version index at each interval until context is
+// done. Interfaces of each new latest stable release are written in a JSON
+// file of dir, named after the version. Errors are logged and polling goes
+// on.
+func watchReleases(ctx context.Context, extractor *gointerfaces.Extractor, interval time.Duration, dir, stateFile string) int {
+	for {
+		if err := writeLatestRelease(ctx, extractor, dir, stateFile); err != nil {
+			extractor.Logger.Errorf("%v", err)
+		}
+		extractor.Logger.Infof("Next check at %s", time.Now().Add(interval).Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case <-time.After(interval):
+		}
+	}
+}
+
+// writeLatestRelease writes interfaces of the latest stable release in a
+// JSON file of dir, unless it is the version in state file, which is then
+// updated
+func writeLatestRelease(ctx context.Context, extractor *gointerfaces.Extractor, dir, stateFile string) error {
+	extractor.ResetIndex()
+	latest, err := extractor.LatestVersions(ctx, 1, false)
+	if err != nil {
+		return err
+	}
+	if len(latest) == 0 {
+		return fmt.Errorf("no stable release in version index")
+	}
+	version := latest[0]
+	state, err := os.ReadFile(stateFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read watch state: %v", err)
+	}
+	if strings.TrimSpace(string(state)) == version {
+		extractor.Logger.Infof("No release after %s", version)
+		return nil
+	}
+	found, err := extractor.InterfacesForVersion(ctx, version)
+	if err != nil {
+		return err
+	}
+	interfaces := gointerfaces.NewInterfaceList()
+	interfaces.AddInterfaces(version, found)
+	interfaces.ResolveAliases()
+	interfaces.LinkEmbeds()
+	path := filepath.Join(dir, "interfaces-"+version+".json")
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(file, interfaces.Rows([]string{version})); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	extractor.Logger.Infof("Wrote interfaces of %s in %s", version, path)
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return fmt.Errorf("could not write watch state: %v", err)
+	}
+	if err := os.WriteFile(stateFile, []byte(version+"\n"), 0644); err != nil {
+		return fmt.Errorf("could not write watch state: %v", err)
+	}
+	return nil
+}
+
+// Exit codes of the program
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// main is the program entry point
+func main() {
+	os.Exit(run())
+}
+
+// usage prints a usage error and returns its exit code
+func usage(message string) int {
+	fmt.Fprintln(os.Stderr, message)
+	return exitUsage
+}
+
+// indexFailure prints an error getting versions in version index, telling
+// to pass versions otherwise if index could not be fetched, and returns its
+// exit code
+func indexFailure(err error) int {
+	var indexError gointerfaces.IndexError
+	if errors.As(err, &indexError) {
+		err = fmt.Errorf("%w, pass versions on command line or with -versions-file instead", err)
+	}
+	return failure(err)
+}
+
+// errorLogger prints errors of failure, in JSON once set up so with
+// -log-format=json
+var errorLogger = gointerfaces.NewLogger(gointerfaces.LevelError)
+
+// failure prints an error and returns its exit code
+func failure(err error) int {
+	errorLogger.Logf(gointerfaces.LevelError, gointerfaces.Fields{"error": err}, "%v", err)
+	return exitFailure
+}
+
+// Version is the version of the tool, set at build time with
+// -ldflags "-X main.Version=1.2.3"
+var Version = "UNKNOWN"
+
+// printVersion prints version of the tool, from build flags or module
+// information, GO version it was built with and stamp of cached results
+func printVersion(w io.Writer) {
+	version := Version
+	if info, ok := debug.ReadBuildInfo(); ok && version == "UNKNOWN" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	fmt.Fprintf(w, "gointerfaces %s\n", version)
+	fmt.Fprintf(w, "Built with %s for %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(w, "Result cache stamp %s\n", gointerfaces.ResultStamp)
+}
+
+// Environment variables overriding default URLs, themselves overridden by
+// flags
+const (
+	envDownloadURL = "GOINTERFACES_DOWNLOAD_URL"
+	envSourceURL   = "GOINTERFACES_SOURCE_URL"
+)
+
+// getenv returns value of environment variable name, or fallback if unset
+// or empty
+func getenv(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// modes are options selecting what is printed and how, checked for
+// conflicts before versions are parsed
+type modes struct {
+	count          int
+	diff           bool
+	diffMethods    bool
+	diffAgainst    string
+	since          string
+	newIn          string
+	implementers   string
+	tui            bool
+	open           string
+	countOnly      bool
+	packagesOnly   bool
+	compared       []string
+	findDuplicates bool
+	digest         bool
+	stream         bool
+	summary        bool
+	limit          int
+	top            int
+	format         string
+	fieldList      string
+	groupByPackage bool
+	groupByVersion bool
+	parser         string
+	order          string
+	linkStyle      string
+	kind           string
+	docMode        string
+	includeLocal   bool
+	tableAlign     string
+	color          string
+}
+
+// checkModes returns an error with usage message if modes conflict or have
+// unknown values
+func checkModes(m modes) error {
+	diffing := m.diff || m.diffMethods
+	if m.diffAgainst != "" {
+		if m.count != 1 {
+			return errors.New("Must pass a single go version to diff against a snapshot")
+		}
+	} else if diffing && m.count != 2 {
+		return errors.New("Must pass two go versions to diff")
+	}
+	if diffing && m.since != "" {
+		return errors.New("Cannot diff versions introducing interfaces")
+	}
+	if m.tui && (diffing || m.since != "" || m.implementers != "") {
+		return errors.New("Cannot explore diffs, introductions or implementers")
+	}
+	if m.open != "" && (m.tui || diffing || m.since != "" || m.implementers != "") {
+		return errors.New("Cannot open an interface while exploring, diffing or finding introductions or implementers")
+	}
+	if m.countOnly && (m.tui || m.open != "" || diffing || m.since != "" || m.implementers != "") {
+		return errors.New("Cannot count interfaces while exploring, opening, diffing or finding introductions or implementers")
+	}
+	if m.packagesOnly && (m.countOnly || m.tui || m.open != "" || diffing || m.since != "" || m.implementers != "") {
+		return errors.New("Cannot list packages while counting, exploring, opening, diffing or finding introductions or implementers")
+	}
+	if m.compared != nil {
+		if len(m.compared) != 2 || m.compared[0] == "" || m.compared[1] == "" {
+			return errors.New("Must pass two packages separated with a comma to compare packages")
+		}
+		if m.findDuplicates || m.packagesOnly || m.countOnly || m.tui || m.open != "" || diffing || m.since != "" || m.implementers != "" {
+			return errors.New("Cannot compare packages while finding duplicates, listing packages, counting, exploring, opening, diffing or finding introductions or implementers")
+		}
+	}
+	if m.findDuplicates && (m.packagesOnly || m.countOnly || m.tui || m.open != "" || diffing || m.since != "" || m.implementers != "") {
+		return errors.New("Cannot find duplicates while listing packages, counting, exploring, opening, diffing or finding introductions or implementers")
+	}
+	if m.digest && (m.compared != nil || m.findDuplicates || m.packagesOnly || m.countOnly || m.tui || m.open != "" || diffing || m.since != "" || m.implementers != "") {
+		return errors.New("Cannot print digest while comparing packages, finding duplicates, listing packages, counting, exploring, opening, diffing or finding introductions or implementers")
+	}
+	switch m.format {
+	case "table", "markdown", "json", "jsonl", "xml", "csv", "tsv", "html", "compact", "go", "template":
+	default:
+		return errors.New("Unknown output format " + m.format)
+	}
+	if m.groupByPackage && m.groupByVersion {
+		return errors.New("Cannot group by package and by version")
+	}
+	if m.stream {
+		if m.format != "jsonl" && (m.format != "table" || !m.groupByVersion || m.fieldList != "") {
+			return errors.New("Can only stream jsonl format or tables grouped by version")
+		}
+		if m.summary || m.digest || m.limit > 0 || m.compared != nil || m.findDuplicates || m.packagesOnly || m.countOnly || m.tui || m.open != "" || diffing || m.newIn != "" || m.since != "" || m.implementers != "" {
+			return errors.New("Cannot stream limited rows, summaries, digests, comparisons, duplicates, packages, counts, explorer, diffs or introductions or implementers")
+		}
+	}
+	if m.parser != gointerfaces.ParserAST && m.parser != gointerfaces.ParserRegexp {
+		return errors.New("Unknown parser " + m.parser)
+	}
+	if m.order != gointerfaces.SortName && m.order != gointerfaces.SortPackage && m.order != gointerfaces.SortFile && m.order != gointerfaces.SortLine && m.order != gointerfaces.SortMethods {
+		return errors.New("Unknown sort order " + m.order)
+	}
+	if m.top < 0 {
+		return errors.New("Number of interfaces to keep with -top must be positive")
+	}
+	if m.linkStyle != gointerfaces.LinkGitHub && m.linkStyle != gointerfaces.LinkPkgDev {
+		return errors.New("Unknown link style " + m.linkStyle)
+	}
+	if m.kind != gointerfaces.KindInterface && m.kind != gointerfaces.KindStruct && m.kind != gointerfaces.KindAlias {
+		return errors.New("Unknown kind " + m.kind)
+	}
+	if m.kind != gointerfaces.KindInterface && m.implementers != "" {
+		return errors.New("Cannot find implementers of other kinds than interface")
+	}
+	if m.docMode != "" && m.docMode != gointerfaces.DocShort && m.docMode != gointerfaces.DocFull {
+		return errors.New("Unknown doc mode " + m.docMode)
+	}
+	if m.includeLocal && m.parser != gointerfaces.ParserAST {
+		return errors.New("Local interfaces are only found by the ast parser")
+	}
+	if m.docMode != "" && m.parser != gointerfaces.ParserAST {
+		return errors.New("Doc comments are only captured by the ast parser")
+	}
+	if m.tableAlign != alignAuto && m.tableAlign != alignLeft && m.tableAlign != alignRight {
+		return errors.New("Unknown table alignment " + m.tableAlign)
+	}
+	if m.color != colorAuto && m.color != colorAlways && m.color != colorNever {
+		return errors.New("Unknown color mode " + m.color)
+	}
+	return nil
+}
+
+// run runs the program and returns its exit code
+func run() int {
+	format := flag.String("format", "table", "output format: table, markdown, json, jsonl, xml, csv, tsv, html, compact, go or template")
+	templateFile := flag.String("template-file", "", "file of GO text template printing result with template format")
+	parserName := flag.String("parser", gointerfaces.ParserAST, "source parser: ast or regex")
+	kind := flag.String("match-kind", gointerfaces.KindInterface, "kind of type declarations to list: interface, struct or alias")
+	linkStyle := flag.String("link-style", gointerfaces.LinkGitHub, "links to sources on github or to documentation on pkgdev")
+	cacheDir := flag.String("cache-dir", gointerfaces.DefaultCacheDir(), "directory where source archives are cached")
+	noCache := flag.Bool("no-cache", false, "always download and parse source archives, without cache")
+	refresh := flag.Bool("refresh", false, "parse source archives again instead of using cached interfaces")
+	resume := flag.Bool("resume", false, "keep partial downloads in cache directory and resume them with range requests")
+	skipVerify := flag.Bool("skip-verify", false, "do not verify checksums of downloaded archives")
+	order := flag.String("sort", gointerfaces.SortName, "sort order in table, csv, tsv, xml, html and compact formats: name, package, file, line or methods, most first")
+	limit := flag.Int("limit", 0, "only print first N rows after sorting, 0 or less for all")
+	top := flag.Int("top", 0, "only keep first N interfaces in sort order, such as largest ones with -sort methods")
+	fieldList := flag.String("fields", "", "comma separated columns of table, markdown, csv and tsv formats: name, package, file, line, link, version, date, methods, count, tags, doc")
+	docMode := flag.String("doc", "", "print doc comments of interfaces: short for their first sentence or full")
+	methods := flag.Bool("methods", false, "print methods, and type set of constraints, beneath interfaces in table")
+	goPackage := flag.String("go-package", "interfaces", "package of GO source printed with go format")
+	tableSep := flag.String("table-sep", "  ", "separator of columns in table format")
+	tableAlign := flag.String("table-align", alignAuto, "alignment of columns in table format: auto, with numbers right aligned, left or right")
+	color := flag.String("color", colorAuto, "color table on terminals: auto, always or never, auto disabled by NO_COLOR environment variable")
+	noHeader := flag.Bool("no-header", false, "do not print header and separator lines in table format")
+	diff := flag.Bool("diff", false, "print interfaces added, removed and moved between two versions")
+	diffMethods := flag.Bool("diff-methods", false, "print methods added and removed in interfaces of both versions")
+	newIn := flag.String("new-in", "", "print interfaces added in this version since latest release of previous minor version")
+	diffAgainst := flag.String("diff-against", "", "diff a single version against latest version of this JSON snapshot")
+	versionsFile := flag.String("versions-file", "", "read versions in this file, one per line, with # comments")
+	latest := flag.Int("latest", 0, "add latest release of the N most recent minor versions")
+	implementers := flag.String("implementers", "", "print types implementing this interface, such as io.Reader, in -src directory or a version")
+	extract := flag.Bool("extract", false, "extract archives in a temporary directory before parsing")
+	keepExtracted := flag.Bool("keep-extracted", false, "extract archives and keep temporary directories")
+	since := flag.String("since", "", "print version introducing interfaces among minor versions since this one")
+	until := flag.String("until", "", "last minor version for -since, defaults to the most recent")
+	prerelease := flag.Bool("include-prerelease", false, "consider betas and release candidates for -latest and -since")
+	watch := flag.Bool("watch", false, "write interfaces of each new stable release in a JSON file, checking version index at each -interval")
+	interval := flag.Duration("interval", 6*time.Hour, "duration between checks of version index with -watch")
+	watchDir := flag.String("watch-dir", ".", "directory of JSON files written with -watch")
+	strict := flag.Bool("strict", false, "fail versions with source files which could not be parsed, instead of skipping them")
+	failFast := flag.Bool("fail-fast", false, "stop at the first version in error, without printing result")
+	tui := flag.Bool("tui", false, "explore interfaces in terminal with fuzzy filtering, opening sources in browser")
+	open := flag.String("open", "", "open source of this interface, such as io.Reader, in browser")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "number of versions processed in parallel")
+	var packages stringList
+	flag.Var(&packages, "package", "only keep interfaces of this package, such as net/http (may be repeated)")
+	name := flag.String("name", "", "only keep interfaces which name matches this regular expression")
+	hasMethod := flag.String("has-method", "", "only keep interfaces declaring a method with this name, such as Close")
+	hasMethodSig := flag.String("has-method-sig", "", "only keep interfaces declaring a method with this signature, such as 'Close() error'")
+	includeUnexported := flag.Bool("include-unexported", false, "also list unexported interfaces")
+	includeAliases := flag.Bool("include-aliases", false, "also list aliases of interfaces, such as type Reader = io.Reader, with ast parser")
+	includeLocal := flag.Bool("include-local", false, "also list interfaces declared in function bodies, named after their function")
+	excludeInternal := flag.Bool("exclude-internal", false, "skip packages with an internal segment in their path")
+	var includeDirs, excludeDirs stringList
+	flag.Var(&includeDirs, "include-dir", "only parse packages in this directory of sources, such as net or net/... (may be repeated)")
+	flag.Var(&excludeDirs, "exclude-dir", "skip packages in this directory of sources, such as net/http (may be repeated)")
+	excludeVendor := flag.Bool("exclude-vendor", false, "skip packages with a vendor segment in their path")
+	allLocations := flag.Bool("all-locations", false, "list all declarations of interfaces declared in several files")
+	minMethods := flag.Int("min-methods", 0, "only keep interfaces with at least N methods")
+	resolveEmbedded := flag.Bool("resolve-embedded", false, "count methods of embedded interfaces instead of one per embedding")
+	summary := flag.Bool("summary", false, "print total number of interfaces and number per package after table")
+	countOnly := flag.Bool("count-only", false, "only print number of interfaces per version, and per package with -summary")
+	comparePackages := flag.String("compare-packages", "", "print interfaces only in each of two packages separated with a comma, such as io,bufio, and in both, for a single version")
+	digest := flag.Bool("digest", false, "only print SHA-256 digest of interfaces, to check that they did not change")
+	findDuplicates := flag.Bool("find-duplicates", false, "print groups of interfaces of several packages with the same method set")
+	packagesOnly := flag.Bool("packages-only", false, "only print packages declaring interfaces, with their number of interfaces with -summary")
+	groupByVersion := flag.Bool("group-by-version", false, "print a table per version in table format")
+	stream := flag.Bool("stream", false, "print each version as soon as it is parsed, in order, in jsonl format or table format grouped by version")
+	groupByPackage := flag.Bool("group-by-package", false, "print a table per package, under its name, in table and markdown formats")
+	mirror := flag.String("mirror", getenv(envDownloadURL, ""), "base URL of source archives, such as https://mirror.example.com/golang/, defaults to "+envDownloadURL+" environment variable")
+	filenameTemplate := flag.String("filename-template", gointerfaces.DefaultFilenameTemplate, "template of names of source archives after base URL, with {{.Version}} such as 1.22.0")
+	sourceURL := flag.String("source-url", getenv(envSourceURL, ""), "format of links to sources, expecting release tag or version label, file and line, defaults to "+envSourceURL+" environment variable if set, else GitHub for GO releases and no links for -tarball")
+	indexURL := flag.String("index-url", gointerfaces.VersionIndexURL, "URL of the JSON version index")
+	indexCache := flag.String("index-cache", "", "file caching the version index, used when it can not be fetched, "+indexCacheFile+" in cache directory by default")
+	historyURL := flag.String("history-url", gointerfaces.ReleaseHistoryURL, "URL of the release history page, giving release dates")
+	retries := flag.Int("retries", 3, "number of retries of downloads failing with network or server errors")
+	timeout := flag.Duration("timeout", 60*time.Second, "maximum duration of each download")
+	quiet := flag.Bool("quiet", false, "only print errors")
+	verbose := flag.Bool("verbose", false, "print diagnostics on downloads and parsed files")
+	logFormat := flag.String("log-format", "text", "format of messages on error output: text or json, a JSON object per line")
+	out := flag.String("out", "", "write result in this file instead of standard output")
+	src := flag.String("src", "", "parse sources in this directory, such as $GOROOT/src, instead of downloading")
+	tarball := flag.String("tarball", "", "parse GO sources of any .tar.gz archive at this URL")
+	var srcPrefixes stringList
+	flag.Var(&srcPrefixes, "src-prefix", "directory of -tarball archive holding packages, such as project-1.0/src (may be repeated)")
+	versionLabel := flag.String("version-label", "", "version of sources in -src directory or -tarball, defaults to go version for -src")
+	dryRun := flag.Bool("dry-run", false, "print archives which would be downloaded and cache status of versions, without processing them")
+	printedVersion := flag.Bool("version", false, "print version of the tool, GO version it was built with and stamp of cached results")
+	flag.Parse()
+	if *printedVersion {
+		printVersion(os.Stdout)
+		return exitOK
+	}
+	// cancel downloads on interruption or termination, to stop watching
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *logFormat != "text" && *logFormat != "json" {
+		return usage("Unknown log format " + *logFormat)
+	}
+	if err := gointerfaces.CheckFilenameTemplate(*filenameTemplate); err != nil {
+		return usage(err.Error())
+	}
+	logger := gointerfaces.NewLogger(gointerfaces.LevelInfo)
+	logger.JSON = *logFormat == "json"
+	errorLogger.JSON = logger.JSON
+	if *quiet {
+		logger.Level = gointerfaces.LevelError
+	} else if *verbose {
+		logger.Level = gointerfaces.LevelDebug
+	}
+	extractor := &gointerfaces.Extractor{
+		Logger:            logger,
+		Timeout:           *timeout,
+		Retries:           *retries,
+		Parser:            *parserName,
+		LinkStyle:         *linkStyle,
+		Kind:              *kind,
+		Doc:               *docMode,
+		CacheDir:          *cacheDir,
+		Refresh:           *refresh,
+		SkipVerify:        *skipVerify,
+		Resume:            *resume,
+		Strict:            *strict,
+		Mirror:            *mirror,
+		SourceURL:         *sourceURL,
+		FilenameTemplate:  *filenameTemplate,
+		ExcludeInternal:   *excludeInternal,
+		IncludeUnexported: *includeUnexported,
+		IncludeLocal:      *includeLocal,
+		IncludeAliases:    *includeAliases,
+		ExcludeVendor:     *excludeVendor,
+		IncludeDirs:       includeDirs,
+		ExcludeDirs:       excludeDirs,
+		AllLocations:      *allLocations,
+		Extract:           *extract || *keepExtracted,
+		KeepExtracted:     *keepExtracted,
+		IndexURL:          *indexURL,
+		IndexCache:        *indexCache,
+		HistoryURL:        *historyURL,
+	}
+	if extractor.IndexCache == "" {
+		extractor.IndexCache = filepath.Join(*cacheDir, indexCacheFile)
+	}
+	if *noCache {
+		extractor.CacheDir = ""
+		extractor.IndexCache = *indexCache
+	}
+	if *watch {
+		stateDir := *cacheDir
+		if *noCache {
+			stateDir = *watchDir
+		}
+		return watchReleases(ctx, extractor, *interval, *watchDir, filepath.Join(stateDir, watchStateFile))
+	}
+	// read versions on command line, on standard input for -, in versions
+	// file and in version index, such as 1.22.0 or go1.22.0 as in tags
+	args := make([]string, 0, flag.NArg())
+	for _, arg := range flag.Args() {
+		if arg != "-" {
+			args = append(args, arg)
+			continue
+		}
+		versions, err := readVersions(os.Stdin)
+		if err != nil {
+			return failure(err)
+		}
+		args = append(args, versions...)
+	}
+	if *versionsFile != "" {
+		versions, err := readVersionsFile(*versionsFile)
+		if err != nil {
+			return failure(err)
+		}
+		args = append(args, versions...)
+	}
+	requested := make([]string, 0, len(args))
+	for _, version := range args {
+		requested = append(requested, strings.TrimPrefix(version, "go"))
+	}
+	*since, *until = strings.TrimPrefix(*since, "go"), strings.TrimPrefix(*until, "go")
+	var indexError gointerfaces.IndexError
+	if *since != "" {
+		between, err := extractor.VersionsBetween(ctx, *since, *until, *prerelease)
+		switch {
+		case errors.As(err, &indexError) && len(requested) > 0:
+			logger.Errorf("Ignoring -since: %v", err)
+		case err != nil:
+			return indexFailure(err)
+		}
+		requested = append(between, requested...)
+	} else if *until != "" {
+		return usage("Must pass -since with -until")
+	}
+	if *newIn != "" {
+		if len(requested) > 0 || *since != "" || *latest > 0 || *diff || *diffMethods || *diffAgainst != "" {
+			return usage("Cannot pass other versions or diff options with -new-in")
+		}
+		version := strings.TrimPrefix(*newIn, "go")
+		previous, err := extractor.PreviousRelease(ctx, version)
+		if err != nil {
+			return indexFailure(err)
+		}
+		requested = []string{previous, version}
+	}
+	if *latest > 0 {
+		latestVersions, err := extractor.LatestVersions(ctx, *latest, *prerelease)
+		switch {
+		case errors.As(err, &indexError) && len(requested) > 0:
+			logger.Errorf("Ignoring -latest: %v", err)
+		case err != nil:
+			return indexFailure(err)
+		}
+		requested = append(latestVersions, requested...)
+	}
+	requested = uniqueVersions(requested, logger)
+	if *src != "" && *tarball != "" {
+		return usage("Cannot parse both -src directory and -tarball")
+	}
+	if *tarball != "" && *versionLabel == "" {
+		return usage("Must pass -version-label with -tarball")
+	}
+	if *src != "" && *versionLabel == "" {
+		label, err := goVersion()
+		if err != nil {
+			return failure(err)
+		}
+		*versionLabel = label
+	}
+	count := len(requested)
+	if *src != "" || *tarball != "" {
+		count++
+	}
+	if count < 1 {
+		return usage("Must pass go version(s) on command line")
+	}
+	// diffing against a snapshot diffs interfaces unless diffing methods
+	if *diffAgainst != "" && !*diffMethods {
+		*diff = true
+	}
+	var compared []string
+	if *comparePackages != "" {
+		compared = strings.Split(*comparePackages, ",")
+	}
+	err := checkModes(modes{
+		count:          count,
+		diff:           *diff,
+		diffMethods:    *diffMethods,
+		diffAgainst:    *diffAgainst,
+		since:          *since,
+		newIn:          *newIn,
+		implementers:   *implementers,
+		tui:            *tui,
+		open:           *open,
+		countOnly:      *countOnly,
+		packagesOnly:   *packagesOnly,
+		compared:       compared,
+		findDuplicates: *findDuplicates,
+		digest:         *digest,
+		stream:         *stream,
+		summary:        *summary,
+		limit:          *limit,
+		top:            *top,
+		format:         *format,
+		fieldList:      *fieldList,
+		groupByPackage: *groupByPackage,
+		groupByVersion: *groupByVersion,
+		parser:         *parserName,
+		order:          *order,
+		linkStyle:      *linkStyle,
+		kind:           *kind,
+		docMode:        *docMode,
+		includeLocal:   *includeLocal,
+		tableAlign:     *tableAlign,
+		color:          *color,
+	})
+	if err != nil {
+		return usage(err.Error())
+	}
+	var tmpl *texttemplate.Template
+	if *format == "template" {
+		if *templateFile == "" {
+			return usage("Must pass -template-file with template format")
+		}
+		parsed, err := parseTemplate(*templateFile)
+		if err != nil {
+			return usage(err.Error())
+		}
+		tmpl = parsed
+	}
+	style := tableStyle{separator: *tableSep, align: *tableAlign, header: !*noHeader}
+	var fieldNames []string
+	if *fieldList != "" {
+		var err error
+		if fieldNames, err = parseFields(*fieldList); err != nil {
+			return usage(fmt.Sprintf("Invalid -fields: %v", err))
+		}
+	}
+	if !token.IsIdentifier(*goPackage) {
+		return usage("Invalid GO package name " + *goPackage)
+	}
+	nameRegexp, err := regexp.Compile(*name)
+	if err != nil {
+		return usage(fmt.Sprintf("Invalid -name regular expression: %v", err))
+	}
+	// open output, standard output by default
+	var output io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return failure(err)
+		}
+		defer file.Close()
+		output = file
+	}
+	// colors are only printed in tables, on terminals unless forced
+	switch *color {
+	case colorAlways:
+		style.color = true
+	case colorAuto:
+		style.color = *out == "" && gointerfaces.IsTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	}
+	if *dryRun {
+		plans := make([]gointerfaces.Plan, 0, len(requested))
+		for _, version := range requested {
+			plan, err := extractor.Plan(version)
+			if err != nil {
+				return failure(err)
+			}
+			plans = append(plans, plan)
+		}
+		if *format == "json" {
+			if err := printJSON(output, plans); err != nil {
+				return failure(err)
+			}
+			return exitOK
+		}
+		printPlans(output, plans, *tarball)
+		return exitOK
+	}
+	if *implementers != "" {
+		dir, version := *src, *versionLabel
+		if dir == "" {
+			if len(requested) != 1 {
+				return usage("Must pass sources directory with -src or a single version to find implementers")
+			}
+			extracted, err := extractor.ExtractVersion(ctx, requested[0])
+			if err != nil {
+				return failure(err)
+			}
+			defer extractor.RemoveExtracted(extracted)
+			dir, version = filepath.Join(extracted, "go", "src"), requested[0]
+		}
+		result, err := extractor.Implementers(ctx, dir, version, *implementers)
+		if err != nil {
+			return failure(err)
+		}
+		if *format == "json" {
+			if err := printJSON(output, result); err != nil {
+				return failure(err)
+			}
+			return exitOK
+		}
+		printImplementers(output, result)
+		return exitOK
+	}
+	// release dates are only fetched when versions are looked up in index
+	var dates map[string]string
+	if *latest > 0 || *since != "" {
+		if dates, err = extractor.ReleaseDates(ctx); err != nil {
+			logger.Errorf("Could not get release dates: %v", err)
+		}
+	}
+	// filter interfaces
+	filter := func(interfaces gointerfaces.InterfaceList) gointerfaces.InterfaceList {
+		if *minMethods > 0 {
+			interfaces = interfaces.Filter(func(interf gointerfaces.Interface, location gointerfaces.Location) bool {
+				return location.MethodCount >= *minMethods
+			})
+		}
+		if len(packages) > 0 {
+			interfaces = interfaces.Filter(func(interf gointerfaces.Interface, location gointerfaces.Location) bool {
+				for _, pkg := range packages {
+					if interf.Package == pkg {
+						return true
+					}
+				}
+				return false
+			})
+		}
+		if *name != "" {
+			interfaces = interfaces.Filter(func(interf gointerfaces.Interface, location gointerfaces.Location) bool {
+				return nameRegexp.MatchString(interf.Name)
+			})
+		}
+		if *hasMethod != "" || *hasMethodSig != "" {
+			signature := gointerfaces.Method{Signature: *hasMethodSig}.Normalized()
+			interfaces = interfaces.Filter(func(interf gointerfaces.Interface, location gointerfaces.Location) bool {
+				for _, method := range location.Methods {
+					if method.Embedded {
+						continue
+					}
+					if (*hasMethod == "" || method.Name == *hasMethod) && (*hasMethodSig == "" || method.Normalized() == signature) {
+						return true
+					}
+				}
+				return false
+			})
+		}
+		return interfaces
+	}
+	// streamed versions are printed as soon as they are parsed
+	var emit func(result)
+	if *stream {
+		printed := 0
+		emit = func(r result) {
+			if r.err != nil {
+				return
+			}
+			interfaces := gointerfaces.NewInterfaceList()
+			interfaces.AddInterfaces(r.version, r.interfaces)
+			interfaces.ResolveAliases()
+			interfaces.LinkEmbeds()
+			if *resolveEmbedded {
+				interfaces.ResolveEmbedded()
+			}
+			interfaces = filter(interfaces)
+			if *top > 0 {
+				interfaces = interfaces.Top([]string{r.version}, *order, *top)
+			}
+			if *format == "jsonl" {
+				rows := interfaces.Rows([]string{r.version})
+				for i := range rows {
+					rows[i].ReleaseDate = dates[r.version]
+				}
+				if err := printJSONLines(output, rows); err != nil {
+					logger.Errorf("Could not print %s: %v", r.version, err)
+				}
+				return
+			}
+			if printed > 0 {
+				fmt.Fprintln(output)
+			}
+			printed++
+			fmt.Fprintf(output, "Version %s\n\n", r.version)
+			printInterfaces(output, interfaces, []string{r.version}, *order, *methods, true, style)
+		}
+	}
+	// iterate on versions and merge results
+	results := make([]result, 0)
+	if *src != "" {
+		found, err := extractor.InterfacesForDirectory(ctx, *src, *versionLabel)
+		results = append(results, result{version: *versionLabel, interfaces: found, err: err})
+		if emit != nil {
+			emit(results[len(results)-1])
+		}
+	}
+	if *tarball != "" {
+		found, err := extractor.InterfacesForTarballPrefixes(ctx, *tarball, srcPrefixes, *versionLabel)
+		results = append(results, result{version: *versionLabel, interfaces: found, err: err})
+		if emit != nil {
+			emit(results[len(results)-1])
+		}
+	}
+	if len(results) > 0 && results[0].err != nil && *failFast {
+		requested = nil
+	}
+	results = append(results, extractVersions(ctx, extractor, requested, *jobs, *failFast, emit)...)
+	if ctx.Err() != nil {
+		logger.Errorf("Interrupted")
+		return exitFailure
+	}
+	status := exitOK
+	if failures := reportStatus(results, extractor); failures > 0 {
+		if *failFast {
+			return exitFailure
+		}
+		status = exitFailure
+	}
+	if *stream {
+		return status
+	}
+	interfaces, versions := aggregate(results)
+	if len(versions) == 0 {
+		// every version failed and was reported
+		return status
+	}
+	if *diffAgainst != "" && len(versions) == 1 {
+		snapshot, from, err := readSnapshot(*diffAgainst)
+		if err != nil {
+			return failure(err)
+		}
+		// snapshot of the same version is labeled with its file
+		if from == versions[0] {
+			from = *diffAgainst
+		}
+		interfaces.AddInterfaces(from, snapshot)
+		versions = []string{from, versions[0]}
+	}
+	interfaces.ResolveAliases()
+	interfaces.LinkEmbeds()
+	if *resolveEmbedded {
+		interfaces.ResolveEmbedded()
+	}
+	interfaces = filter(interfaces)
+	if *top > 0 {
+		interfaces = interfaces.Top(versions, *order, *top)
+	}
+	// print the result
+	if *open != "" {
+		if err := openInterface(interfaces, versions, *open); err != nil {
+			return failure(err)
+		}
+		return status
+	}
+	if *tui {
+		if err := explore(ctx, interfaces, versions); err != nil {
+			return failure(err)
+		}
+		return status
+	}
+	if *since != "" {
+		result := interfaces.Introductions(versions)
+		for r := range result {
+			result[r].ReleaseDate = dates[result[r].IntroducedIn]
+		}
+		if *format == "json" {
+			if err := printJSON(output, result); err != nil {
+				return failure(err)
+			}
+			return status
+		}
+		printIntroductions(output, result)
+		return status
+	}
+	if *diffMethods {
+		if len(versions) != 2 {
+			// a version failed and was reported
+			return status
+		}
+		result := interfaces.MethodDiff(versions[0], versions[1])
+		if *format == "json" {
+			if err := printJSON(output, result); err != nil {
+				return failure(err)
+			}
+			return status
+		}
+		printMethodDiff(output, versions[0], versions[1], result)
+		return status
+	}
+	if *newIn != "" {
+		if len(versions) != 2 {
+			// a version failed and was reported
+			return status
+		}
+		result := interfaces.Diff(versions[0], versions[1])
+		switch *format {
+		case "json":
+			if err := printJSON(output, result.Added); err != nil {
+				return failure(err)
+			}
+		case "markdown":
+			lines := make([][]string, 0, len(result.Added))
+			for _, row := range result.Added {
+				lines = append(lines, []string{row.QualifiedName(), "[" + row.SourceFile + ":" + row.LineNumber + "](" + row.Link + ")"})
+			}
+			printMarkdownTable(output, []string{"Interface", "Source"}, lines)
+		default:
+			printAdditions(output, result)
+		}
+		return status
+	}
+	if *diff {
+		if len(versions) != 2 {
+			// a version failed and was reported
+			return status
+		}
+		result := interfaces.Diff(versions[0], versions[1])
+		if *format == "json" {
+			if err := printJSON(output, result); err != nil {
+				return failure(err)
+			}
+			return status
+		}
+		printDiff(output, result)
+		return status
+	}
+	if *countOnly {
+		counts := interfaces.Counts(versions, *summary)
+		if *format == "json" {
+			if err := printJSON(output, counts); err != nil {
+				return failure(err)
+			}
+			return status
+		}
+		printCounts(output, counts)
+		return status
+	}
+	if compared != nil {
+		if len(versions) != 1 {
+			return usage("Must pass a single go version to compare packages")
+		}
+		comparison := interfaces.ComparePackages(strings.TrimSpace(compared[0]), strings.TrimSpace(compared[1]), versions[0])
+		if *format == "json" {
+			if err := printJSON(output, comparison); err != nil {
+				return failure(err)
+			}
+			return status
+		}
+		printComparison(output, comparison)
+		return status
+	}
+	if *digest {
+		sum, err := interfaces.Digest(versions)
+		if err != nil {
+			return failure(err)
+		}
+		if *format == "json" {
+			if err := printJSON(output, map[string]interface{}{"versions": versions, "sha256": sum}); err != nil {
+				return failure(err)
+			}
+			return status
+		}
+		fmt.Fprintln(output, sum)
+		return status
+	}
+	if *findDuplicates {
+		duplicates := interfaces.Duplicates(versions)
+		if *format == "json" {
+			if err := printJSON(output, duplicates); err != nil {
+				return failure(err)
+			}
+			return status
+		}
+		printDuplicates(output, duplicates)
+		return status
+	}
+	if *packagesOnly {
+		packages := interfaces.Packages(versions)
+		if *format == "json" {
+			var result interface{} = packages
+			if !*summary {
+				names := make([]string, len(packages))
+				for p, pkg := range packages {
+					names[p] = pkg.Package
+				}
+				result = names
+			}
+			if err := printJSON(output, result); err != nil {
+				return failure(err)
+			}
+			return status
+		}
+		printPackages(output, packages, *summary)
+		return status
+	}
+	// rows are interfaces in formats printing a line per interface, and
+	// interfaces in a version in other ones
+	perInterface := (fieldNames == nil && (*format == "table" || *format == "markdown")) || *format == "compact" || *format == "html" || *format == "go"
+	more := 0
+	if *limit > 0 && perInterface && len(interfaces) > *limit {
+		more = len(interfaces) - *limit
+		interfaces = interfaces.Top(versions, *order, *limit)
+	}
+	rows := interfaces.Rows(versions)
+	if *limit > 0 && !perInterface && len(rows) > *limit {
+		// rows of JSON formats are sorted by package and name
+		if *format == "csv" || *format == "tsv" || *format == "xml" || fieldNames != nil {
+			sort.SliceStable(rows, func(i, j int) bool {
+				return gointerfaces.Less(*order, rows[i].Interface, rows[i].Location, rows[j].Interface, rows[j].Location)
+			})
+		}
+		more = len(rows) - *limit
+		rows = rows[:*limit]
+	}
+	for r := range rows {
+		rows[r].ReleaseDate = dates[rows[r].Version]
+	}
+	switch *format {
+	case "json":
+		if err := printJSON(output, rows); err != nil {
+			return failure(err)
+		}
+	case "csv", "tsv":
+		if fieldNames == nil {
+			fieldNames = csvFields(versions)
+		}
+		separator := ','
+		if *format == "tsv" {
+			separator = '\t'
+		}
+		if err := printCSV(output, rows, *order, fieldNames, separator); err != nil {
+			return failure(err)
+		}
+	case "markdown":
+		if fieldNames != nil {
+			header, lines := fieldLines(rows, *order, fieldNames)
+			printMarkdownTable(output, header, lines)
+		} else {
+			if *groupByPackage {
+				packageGroups(output, interfaces, versions, "## %s\n\n", func(group gointerfaces.InterfaceList) {
+					printMarkdown(output, group, versions, *order, false)
+				})
+			} else {
+				printMarkdown(output, interfaces, versions, *order, true)
+			}
+		}
+	case "jsonl":
+		if err := printJSONLines(output, rows); err != nil {
+			return failure(err)
+		}
+	case "xml":
+		if err := printXML(output, rows, *order); err != nil {
+			return failure(err)
+		}
+	case "template":
+		if err := printTemplate(output, tmpl, versions, rows); err != nil {
+			return failure(err)
+		}
+	case "go":
+		if err := printGo(output, interfaces, versions, *goPackage); err != nil {
+			return failure(err)
+		}
+	case "compact":
+		printCompact(output, interfaces, versions, *order)
+	case "html":
+		if err := printHTML(output, interfaces, versions, *order); err != nil {
+			return failure(err)
+		}
+	default:
+		logger.Infof("Printing table...")
+		if fieldNames != nil {
+			header, lines := fieldLines(rows, *order, fieldNames)
+			colors := make([]string, len(fieldNames))
+			for f, name := range fieldNames {
+				colors[f] = fieldColors[name]
+			}
+			printAligned(output, header, lines, nil, colors, style)
+		} else if *groupByVersion && len(versions) > 1 {
+			for i, version := range versions {
+				if i > 0 {
+					fmt.Fprintln(output)
+				}
+				fmt.Fprintf(output, "Version %s\n\n", version)
+				printInterfaces(output, interfaces.Version(version), []string{version}, *order, *methods, true, style)
+			}
+		} else if *groupByPackage {
+			packageGroups(output, interfaces, versions, "Package %s\n\n", func(group gointerfaces.InterfaceList) {
+				printInterfaces(output, group, versions, *order, *methods, false, style)
+			})
+		} else {
+			printInterfaces(output, interfaces, versions, *order, *methods, true, style)
+		}
+		if *summary {
+			printSummary(output, interfaces.Sorted(versions, gointerfaces.SortName))
+		}
+	}
+	// rows left out are told after printed ones, even if quiet
+	if more > 0 {
+		fmt.Fprintf(os.Stderr, "... and %d more\n", more)
+	}
+	return status
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/c4s4/gointerfaces"
+	"github.com/c4s4/gointerfaces/internal/testserver"
+)
+
+func TestAggregateVersions(t *testing.T) {
+	// 1.21.1 is not served and fails, without failing other versions
+	extractor := &gointerfaces.Extractor{
+		Mirror:     testserver.ServeArchives(t, filepath.Join("..", "..", "testdata")),
+		SkipVerify: true,
+		CacheDir:   t.TempDir(),
+	}
+	results := extractVersions(context.Background(), extractor, []string{"1.21.0", "1.21.1", "1.22.0"}, 2, false, nil)
+	if results[1].err == nil {
+		t.Errorf("extracting 1.21.1 returned no error")
+	}
+	interfaces, versions := aggregate(results)
+	if !reflect.DeepEqual(versions, []string{"1.21.0", "1.22.0"}) {
+		t.Fatalf("aggregated versions %q, expected 1.21.0 and 1.22.0", versions)
+	}
+	var buffer bytes.Buffer
+	printCompact(&buffer, interfaces, versions, gointerfaces.SortName)
+	expected := "" +
+		"net.Conn\tsrc/net/net.go:6\n" +
+		"sort.Interface\tsrc/sort/sort.go:4\n" +
+		"io.ReadWriter\tsrc/io/io.go:14\n" +
+		"io.Reader\tsrc/io/io.go:4\n" +
+		"io.Writer\tsrc/io/io.go:9\n"
+	if buffer.String() != expected {
+		t.Errorf("printCompact printed:\n%s\nexpected:\n%s", buffer.String(), expected)
+	}
+}
+
+func TestPrintAligned(t *testing.T) {
+	header := []string{"Interface", "Package", "Line"}
+	lines := [][]string{
+		{"Reader", "io", "86"},
+		{"ResponseWriter", "net/http", "1234"},
+		{"Conn", "net", "-"},
+	}
+	tests := []struct {
+		name     string
+		style    tableStyle
+		expected string
+	}{
+		{
+			name:  "auto",
+			style: tableStyle{separator: "  ", align: alignAuto, header: true},
+			expected: "" +
+				"Interface       Package   Line\n" +
+				":-------------  :-------  ---:\n" +
+				"Reader          io          86\n" +
+				"ResponseWriter  net/http  1234\n" +
+				"Conn            net          -\n",
+		},
+		{
+			name:  "left",
+			style: tableStyle{separator: " | ", align: alignLeft, header: true},
+			expected: "" +
+				"Interface      | Package  | Line\n" +
+				":------------- | :------- | :---\n" +
+				"Reader         | io       | 86\n" +
+				"ResponseWriter | net/http | 1234\n" +
+				"Conn           | net      | -\n",
+		},
+		{
+			name:  "no header",
+			style: tableStyle{separator: "  ", align: alignRight, header: false},
+			expected: "" +
+				"        Reader        io    86\n" +
+				"ResponseWriter  net/http  1234\n" +
+				"          Conn       net     -\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			printAligned(&buffer, header, lines, nil, nil, test.style)
+			if buffer.String() != test.expected {
+				t.Errorf("printAligned printed:\n%s\nexpected:\n%s", buffer.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestPrintAlignedColorsOutsidePadding(t *testing.T) {
+	var buffer bytes.Buffer
+	lines := [][]string{{"Reader", "io"}, {"ReadWriter", "io"}}
+	style := tableStyle{separator: "  ", align: alignAuto, header: false, color: true}
+	printAligned(&buffer, []string{"Interface", "Package"}, lines, nil, []string{colorName, ""}, style)
+	plain := strings.ReplaceAll(strings.ReplaceAll(buffer.String(), colorName, ""), colorReset, "")
+	expected := "Reader      io\nReadWriter  io\n"
+	if plain != expected {
+		t.Errorf("printAligned printed without colors:\n%s\nexpected:\n%s", plain, expected)
+	}
+}
+
+func TestPrintCSV(t *testing.T) {
+	reader := gointerfaces.Interface{Name: "Reader", Package: "io"}
+	location := gointerfaces.Location{SourceFile: "src/io/io.go", LineNumber: "4", Link: "https://example.com/io.go?a=1,b=2"}
+	tests := []struct {
+		versions []string
+		expected string
+	}{
+		{
+			versions: []string{"1.22.0"},
+			expected: "Interface,Package,SourceFile,Line,Link\n" +
+				"Reader,io,src/io/io.go,4,\"https://example.com/io.go?a=1,b=2\"\n",
+		},
+		{
+			versions: []string{"1.21.0", "1.22.0"},
+			expected: "Interface,Package,Version,SourceFile,Line,Link\n" +
+				"Reader,io,1.21.0,src/io/io.go,4,\"https://example.com/io.go?a=1,b=2\"\n" +
+				"Reader,io,1.22.0,src/io/io.go,4,\"https://example.com/io.go?a=1,b=2\"\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.versions, ","), func(t *testing.T) {
+			rows := make([]gointerfaces.Row, 0, len(test.versions))
+			for _, version := range test.versions {
+				rows = append(rows, gointerfaces.Row{Interface: reader, Version: version, Location: location})
+			}
+			var buffer bytes.Buffer
+			if err := printCSV(&buffer, rows, gointerfaces.SortName, csvFields(test.versions), ','); err != nil {
+				t.Fatalf("printCSV returned error: %v", err)
+			}
+			if buffer.String() != test.expected {
+				t.Errorf("printCSV printed:\n%s\nexpected:\n%s", buffer.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestGetenv(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		unset    bool
+		expected string
+	}{
+		{name: "unset", unset: true, expected: "default"},
+		{name: "empty", value: "", expected: "default"},
+		{name: "set", value: "http://localhost:8080/", expected: "http://localhost:8080/"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// environment is restored after test even if unset
+			t.Setenv(envDownloadURL, test.value)
+			if test.unset {
+				os.Unsetenv(envDownloadURL)
+			}
+			if value := getenv(envDownloadURL, "default"); value != test.expected {
+				t.Errorf("getenv returned %q, expected %q", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestEnvironmentURLs(t *testing.T) {
+	// server records requested archive and has none
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	t.Setenv(envDownloadURL, server.URL+"/golang/")
+	t.Setenv(envSourceURL, "http://localhost:8080/go/blob/%s/%s#L%s")
+	extractor := &gointerfaces.Extractor{
+		Mirror:     getenv(envDownloadURL, ""),
+		SourceURL:  getenv(envSourceURL, ""),
+		CacheDir:   t.TempDir(),
+		SkipVerify: true,
+	}
+	if _, err := extractor.InterfacesForVersion(context.Background(), "1.22.0"); err == nil {
+		t.Fatal("InterfacesForVersion returned no error for missing archive")
+	}
+	if expected := "/golang/go1.22.0.src.tar.gz"; requested != expected {
+		t.Errorf("requested archive is %s, expected %s", requested, expected)
+	}
+	source := "package io\n\ntype Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n"
+	interfaces, err := extractor.ParseSource("go/src/io/io.go", strings.NewReader(source), "src", "1.22.0")
+	if err != nil {
+		t.Fatalf("ParseSource returned error: %v", err)
+	}
+	link := interfaces[gointerfaces.Interface{Name: "Reader", Package: "io"}].Link
+	if expected := "http://localhost:8080/go/blob/go1.22.0/src/io/io.go#L3"; link != expected {
+		t.Errorf("link is %s, expected %s", link, expected)
+	}
+}
+
+func TestOpenInterfaceErrors(t *testing.T) {
+	// tarballs have no links, thus no browser is opened
+	interfaces := gointerfaces.NewInterfaceList()
+	interfaces.AddInterfaces("1.0", map[gointerfaces.Interface]gointerfaces.Location{
+		{Name: "Conn", Package: "net"}:        {SourceFile: "net/net.go", LineNumber: "6"},
+		{Name: "Conn", Package: "crypto/tls"}: {SourceFile: "crypto/tls/conn.go", LineNumber: "30"},
+		{Name: "Run.Local", Package: "foo"}:   {SourceFile: "foo/foo.go", LineNumber: "12"},
+	})
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "net.Conn", expected: "no link for net.Conn"},
+		{name: "Conn", expected: "interface Conn is declared in several packages, pass one of crypto/tls.Conn, net.Conn"},
+		{name: "foo.Run.Local", expected: "no link for foo.Run.Local"},
+		{name: "io.Reader", expected: "could not find interface io.Reader"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := openInterface(interfaces, []string{"1.0"}, test.name)
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("openInterface returned error %v, expected %s", err, test.expected)
+			}
+		})
+	}
+}
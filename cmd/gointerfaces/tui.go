@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/c4s4/gointerfaces"
+)
+
+// Keys read in raw terminal mode
+const (
+	keyCtrlC     = 3
+	keyBackspace = 8
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Terminal sequences of the explorer
+const (
+	clearScreen  = "\033[H\033[2J"
+	reverseVideo = "\033[7m"
+	resetVideo   = "\033[0m"
+)
+
+// entry is an interface listed in explorer, with the link of its latest
+// declaration
+type entry struct {
+	label string
+	link  string
+}
+
+// match is an entry matching the query with a score, lower being better
+type match struct {
+	entry
+	score int
+}
+
+// fuzzyScore tells if all characters of query appear in label in this
+// order, ignoring case, and returns the length of the span where they are
+// first found, so that close characters score better
+func fuzzyScore(label, query string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	label = strings.ToLower(label)
+	query = strings.ToLower(query)
+	start, q := -1, 0
+	for i, c := range label {
+		if c != rune(query[q]) {
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		q++
+		if q == len(query) {
+			return i - start, true
+		}
+	}
+	return 0, false
+}
+
+// filterEntries returns entries matching query, best scores first
+func filterEntries(entries []entry, query string) []match {
+	matches := make([]match, 0, len(entries))
+	for _, e := range entries {
+		if score, ok := fuzzyScore(e.label, query); ok {
+			matches = append(matches, match{entry: e, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score < matches[j].score
+	})
+	return matches
+}
+
+// openBrowser opens url in the default browser of the platform
+func openBrowser(url string) error {
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("open", url)
+	case "windows":
+		// start command of cmd would interpret special characters of url
+		command = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		command = exec.Command("xdg-open", url)
+	}
+	if err := command.Start(); err != nil {
+		return fmt.Errorf("could not open browser: %v", err)
+	}
+	// release resources of the process which may outlive the program
+	go command.Wait()
+	return nil
+}
+
+// openInterface opens in the browser the latest declaration of interface
+// with given name, qualified with its package, such as io.Reader, or not if
+// a single package declares it
+func openInterface(interfaceList gointerfaces.InterfaceList, versions []string, name string) error {
+	sorted := interfaceList.Sorted(versions, gointerfaces.SortPackage)
+	// qualified names are matched first, as names of local interfaces have
+	// a dot, such as Func.Local
+	found := make([]gointerfaces.Interface, 0)
+	for _, i := range sorted {
+		if i.QualifiedName() == name {
+			found = append(found, i)
+		}
+	}
+	if len(found) == 0 {
+		pkg, short := "", name
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			pkg, short = name[:dot], name[dot+1:]
+		}
+		for _, i := range sorted {
+			if i.Name == short && (pkg == "" || i.Package == pkg) {
+				found = append(found, i)
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return fmt.Errorf("could not find interface %s", name)
+	case 1:
+		link := interfaceList.Latest(found[0], versions).Link
+		if link == "" {
+			return fmt.Errorf("no link for %s", found[0].QualifiedName())
+		}
+		return openBrowser(link)
+	default:
+		names := make([]string, len(found))
+		for index, i := range found {
+			names[index] = i.QualifiedName()
+		}
+		return fmt.Errorf("interface %s is declared in several packages, pass one of %s", name, strings.Join(names, ", "))
+	}
+}
+
+// stty runs stty command on the terminal and returns its output
+func stty(args ...string) (string, error) {
+	command := exec.Command("stty", args...)
+	command.Stdin = os.Stdin
+	output, err := command.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// terminalHeight returns the number of lines of the terminal, 24 if unknown
+func terminalHeight() int {
+	size, err := stty("size")
+	if err != nil {
+		return 24
+	}
+	fields := strings.Fields(size)
+	if len(fields) != 2 {
+		return 24
+	}
+	height, err := strconv.Atoi(fields[0])
+	if err != nil || height < 3 {
+		return 24
+	}
+	return height
+}
+
+// renderExplorer draws the query and the matches that fit in height, with
+// selected one in reverse video and message on the last line
+func renderExplorer(w io.Writer, query string, matches []match, total, selected, height int, message string) {
+	prompt := fmt.Sprintf("%d/%d > %s", len(matches), total, query)
+	// in raw mode, line feeds do not return carriage
+	fmt.Fprint(w, clearScreen)
+	fmt.Fprintf(w, "%s\r\n", prompt)
+	visible := height - 2
+	first := 0
+	if selected >= visible {
+		first = selected - visible + 1
+	}
+	for i := first; i < len(matches) && i < first+visible; i++ {
+		if i == selected {
+			fmt.Fprintf(w, "%s%s%s\r\n", reverseVideo, matches[i].label, resetVideo)
+		} else {
+			fmt.Fprintf(w, "%s\r\n", matches[i].label)
+		}
+	}
+	fmt.Fprintf(w, "\033[%d;1H%s", height, message)
+	// put cursor back at the end of the query
+	fmt.Fprintf(w, "\033[1;%dH", len(prompt)+1)
+}
+
+// readKeys sends chunks read on input to keys until an error occurs
+func readKeys(input io.Reader, keys chan<- []byte) {
+	defer close(keys)
+	for {
+		buffer := make([]byte, 16)
+		n, err := input.Read(buffer)
+		if n > 0 {
+			keys <- buffer[:n]
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// explore opens a terminal user interface listing interfaces with fuzzy
+// filtering on package and name while typing. Enter opens the link of
+// selected interface in the browser, Escape or Ctrl-C quits.
+func explore(ctx context.Context, interfaceList gointerfaces.InterfaceList, versions []string) error {
+	if !gointerfaces.IsTerminal(os.Stdin) || !gointerfaces.IsTerminal(os.Stdout) {
+		return fmt.Errorf("interactive mode requires a terminal")
+	}
+	entries := make([]entry, 0, len(interfaceList))
+	for _, i := range interfaceList.Sorted(versions, gointerfaces.SortPackage) {
+		latest := interfaceList.Latest(i, versions)
+		entries = append(entries, entry{label: i.QualifiedName(), link: latest.Link})
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("could not get terminal settings: %v", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return fmt.Errorf("could not set terminal in raw mode: %v", err)
+	}
+	defer func() {
+		stty(saved)
+		fmt.Fprint(os.Stdout, clearScreen)
+	}()
+	keys := make(chan []byte)
+	go readKeys(os.Stdin, keys)
+	height := terminalHeight()
+	query, selected, message := "", 0, "Type to filter, arrows to select, Enter to open, Esc to quit"
+	matches := filterEntries(entries, query)
+	for {
+		renderExplorer(os.Stdout, query, matches, len(entries), selected, height, message)
+		var key []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			key = k
+		}
+		message = ""
+		switch {
+		case key[0] == keyCtrlC || (key[0] == keyEscape && len(key) == 1):
+			return nil
+		case key[0] == keyEnter:
+			if selected < len(matches) && matches[selected].link == "" {
+				message = fmt.Sprintf("no link for %s", matches[selected].label)
+			} else if selected < len(matches) {
+				if err := openBrowser(matches[selected].link); err != nil {
+					message = err.Error()
+				} else {
+					message = "Opened " + matches[selected].link
+				}
+			}
+		case string(key) == "\033[A" || key[0] == keyCtrlP:
+			if selected > 0 {
+				selected--
+			}
+		case string(key) == "\033[B" || key[0] == keyCtrlN:
+			if selected < len(matches)-1 {
+				selected++
+			}
+		case key[0] == keyDelete || key[0] == keyBackspace:
+			if query != "" {
+				query = query[:len(query)-1]
+				matches, selected = filterEntries(entries, query), 0
+			}
+		case key[0] == keyCtrlU:
+			query = ""
+			matches, selected = filterEntries(entries, query), 0
+		case key[0] != keyEscape:
+			for _, c := range string(key) {
+				if unicode.IsPrint(c) && c < unicode.MaxASCII {
+					query += string(c)
+				}
+			}
+			matches, selected = filterEntries(entries, query), 0
+		}
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/c4s4/gointerfaces
 
-go 1.16
+go 1.18
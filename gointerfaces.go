@@ -1,237 +1,3308 @@
-package main
+// Package gointerfaces extracts public interfaces of GO standard library
+// from source archives of GO releases, or from sources on disk.
+package gointerfaces
 
 import (
 	"archive/tar"
 	"bufio"
+	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
 	"io"
+	"io/fs"
+	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 )
 
 const (
 	oldSrcURL = "https://storage.googleapis.com/google-code-archive-downloads/v2/code.google.com/go/"
 	newSrcURL = "https://storage.googleapis.com/golang/"
-	oldSrcDir = "src/pkg"
-	newSrcDir = "src"
-	// expects go version, source file and line number
-	sourceURL       = "https://github.com/golang/go/blob/go%s/%s#L%s"
-	interfaceRegexp = `^type\s+([A-Z]\w*)\s+interface\s*{`
+	// delay before first retry of a download, doubled on each retry
+	retryDelay = time.Second
+	// root directory of archives, holding the GO repository
+	archiveRoot = "go/"
+	oldSrcDir   = "src/pkg"
+	newSrcDir   = "src"
+	// expects package and interface name
+	docURL = "https://pkg.go.dev/%s#%s"
+	// version such as 1.21.5, 1.21rc1 or 1.22beta1
+	versionRegexp = `^(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:(beta|rc)(\d+))?$`
+	// source of interfaces on GitHub, expects release tag, source file and
+	// line number
+	GitHubSourceURL = "https://github.com/golang/go/blob/%s/%s#L%s"
+	// name of source archives on download server, with Version field
+	DefaultFilenameTemplate = "go{{.Version}}.src.tar.gz"
+	// index of all GO releases
+	VersionIndexURL = "https://go.dev/dl/?mode=json&include=all"
+	// release history, giving release dates which version index lacks
+	ReleaseHistoryURL = "https://go.dev/doc/devel/release"
+	// release date of a version in release history, such as go1.22.0
+	// (released 2024-02-06)
+	releasedRegexp = `go(\d+(?:\.\d+)*(?:(?:beta|rc)\d+)?)\s+\(released\s+(\d{4}-\d{2}-\d{2})\)`
+	// type declaration, brace may be on the following line, first %s is
+	// the pattern of names and second one the pattern of declared type
+	interfaceRegexp = `^type\s+(%s)(\[.*\])?%s`
+	// type declaration in a grouped type block
+	groupedInterfaceRegexp = `^\s+(%s)(\[.*\])?%s`
+	// names of exported interfaces and of all interfaces
+	exportedNameRegexp   = `[A-Z]\w*`
+	anyNameRegexp        = `[A-Za-z_]\w*`
+	typeBlockStartRegexp = `^type\s*\(\s*(//.*)?$`
+	typeBlockEndRegexp   = `^\)`
+	openingBraceRegexp   = `^\s*{`
+	// operating systems and architectures in source file suffixes
+	platforms = `aix|android|darwin|dragonfly|freebsd|hurd|illumos|ios|js|linux|nacl|netbsd|openbsd|plan9|solaris|wasip1|windows|zos|` +
+		`386|amd64|amd64p32|arm|arm64|loong64|mips|mipsle|mips64|mips64le|ppc64|ppc64le|riscv64|s390x|wasm`
+	// source file for a given operating system and/or architecture
+	platformFileRegexp = `_(` + platforms + `)(_(` + platforms + `))?\.go$`
 )
 
+// Parsers to extract interfaces from source files
+const (
+	ParserAST    = "ast"
+	ParserRegexp = "regex"
+)
+
+// Styles of links to interfaces
+const (
+	LinkGitHub = "github"
+	LinkPkgDev = "pkgdev"
+)
+
+// Kinds of type declarations to extract
+const (
+	KindInterface = "interface"
+	KindStruct    = "struct"
+	KindAlias     = "alias"
+)
+
+// InterfaceKind tells if an interface declares methods, a type set, which is
+// a constraint, or both
+type InterfaceKind string
+
+// Kinds of interfaces found by the AST parser
+const (
+	KindMethods    InterfaceKind = "methods"
+	KindConstraint InterfaceKind = "constraint"
+	KindMixed      InterfaceKind = "mixed"
+)
+
+// kindRegexps are patterns of declared types by kind, capturing opening
+// brace if on the same line
+var kindRegexps = map[string]string{
+	KindInterface: `\s+interface\s*({|//|$)`,
+	KindStruct:    `\s+struct\s*({|//|$)`,
+	KindAlias:     `\s*=\s*()\S`,
+}
+
+// Doc comments captured by the AST parser
+const (
+	DocShort = "short"
+	DocFull  = "full"
+)
+
+// Log levels, from least to most verbose
+const (
+	LevelError = iota
+	LevelInfo
+	LevelDebug
+)
+
+// Names of log levels in JSON logs
+var levelNames = map[int]string{
+	LevelError: "error",
+	LevelInfo:  "info",
+	LevelDebug: "debug",
+}
+
+// Fields are structured data of a log message, such as version or count
+type Fields map[string]interface{}
+
+// Logger prints messages up to a level, a nil logger prints nothing
+type Logger struct {
+	Level  int
+	Writer io.Writer
+	// JSON prints messages as JSON objects, one per line, with level, msg
+	// and time fields, and fields of the message
+	JSON bool
+	// Terminal tells if writer is a terminal, where status is updated in
+	// place on the last line
+	Terminal bool
+	status   string
+	mutex    sync.Mutex
+}
+
+// NewLogger builds a logger printing on error output up to given level
+func NewLogger(level int) *Logger {
+	return &Logger{Level: level, Writer: os.Stderr, Terminal: IsTerminal(os.Stderr)}
+}
+
+// IsTerminal tells if file is a terminal
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// clearLine is the terminal sequence to erase current line
+const clearLine = "\r\033[K"
+
+// Logf prints a message with fields if level is enabled, beneath status on
+// terminals. Fields are only printed in JSON, thus message should mention
+// them for text logs.
+func (l *Logger) Logf(level int, fields Fields, format string, args ...interface{}) {
+	if l == nil || level > l.Level {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.JSON {
+		l.printJSON(level, fields, fmt.Sprintf(format, args...))
+		return
+	}
+	if l.status != "" {
+		fmt.Fprint(l.Writer, clearLine)
+	}
+	fmt.Fprintf(l.Writer, format+"\n", args...)
+	if l.status != "" {
+		fmt.Fprint(l.Writer, l.status)
+	}
+}
+
+// printJSON prints a message as a JSON object on a line
+func (l *Logger) printJSON(level int, fields Fields, message string) {
+	object := make(Fields, len(fields)+3)
+	for key, value := range fields {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		object[key] = value
+	}
+	object["level"] = levelNames[level]
+	object["msg"] = message
+	object["time"] = time.Now().UTC().Format(time.RFC3339)
+	line, err := json.Marshal(object)
+	if err != nil {
+		line, _ = json.Marshal(Fields{"level": levelNames[level], "msg": message})
+	}
+	fmt.Fprintf(l.Writer, "%s\n", line)
+}
+
+// Statusf prints a progress status. On terminals, it replaces previous
+// status on the last line until cleared, otherwise it is printed as a
+// progress message.
+func (l *Logger) Statusf(format string, args ...interface{}) {
+	if l == nil || !l.Terminal || l.JSON {
+		l.Infof(format, args...)
+		return
+	}
+	if l.Level < LevelInfo {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.status = fmt.Sprintf(format, args...)
+	fmt.Fprint(l.Writer, clearLine+l.status)
+}
+
+// ClearStatus erases status printed on terminals
+func (l *Logger) ClearStatus() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.status != "" {
+		fmt.Fprint(l.Writer, clearLine)
+		l.status = ""
+	}
+}
+
+// Errorf prints an error message
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.Logf(LevelError, nil, format, args...)
+}
+
+// Infof prints a progress message
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.Logf(LevelInfo, nil, format, args...)
+}
+
+// Debugf prints a diagnostic message
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.Logf(LevelDebug, nil, format, args...)
+}
+
+// countingReader is a reader counting bytes read
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+// Read reads from underlying reader and counts bytes
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// Extractor extracts interfaces from GO sources
+type Extractor struct {
+	// Parser is the parser to use, ParserAST if empty
+	Parser string
+	// LinkStyle is the style of links to interfaces, LinkGitHub if empty
+	LinkStyle string
+	// Kind is the kind of type declarations to extract, listed as
+	// interfaces, KindInterface if empty
+	Kind string
+	// Doc captures doc comments of interfaces with the AST parser, their
+	// first sentence with DocShort or full text with DocFull, none if empty
+	Doc string
+	// CacheDir is the directory where source archives and interfaces found
+	// in them are cached, no cache if empty
+	CacheDir string
+	// Refresh parses archives again instead of using cached interfaces
+	Refresh bool
+	// IncludeLocal extracts interfaces declared in function bodies too, with
+	// the AST parser
+	IncludeLocal bool
+	// IncludeUnexported extracts unexported interfaces too
+	IncludeUnexported bool
+	// ExcludeInternal skips packages with an internal segment in their path
+	ExcludeInternal bool
+	// ExcludeVendor skips packages with a vendor segment in their path
+	ExcludeVendor bool
+	// IncludeAliases also lists aliases of named types with the AST parser,
+	// which should then be resolved with InterfaceList.ResolveAliases
+	IncludeAliases bool
+	// IncludeDirs only keeps packages in these directories, relative to
+	// source directory such as net or net/..., all packages if empty
+	IncludeDirs []string
+	// ExcludeDirs skips packages in these directories, relative to source
+	// directory such as cmd or cmd/...
+	ExcludeDirs []string
+	// AllLocations keeps all declarations of interfaces declared several
+	// times in a version as alternates of the canonical one
+	AllLocations bool
+	// Extract extracts archives in a temporary directory and parses files
+	// there, instead of parsing them while reading archives
+	Extract bool
+	// KeepExtracted does not remove temporary directories of extracted
+	// archives
+	KeepExtracted bool
+	// Mirror is the base URL of source archives, official locations if empty
+	Mirror string
+	// FilenameTemplate is the template of names of source archives after
+	// base URL, with Version field, DefaultFilenameTemplate if empty
+	FilenameTemplate string
+	// SourceURL is the format of links to sources, expecting release tag,
+	// or version label of tarballs, source file and line number.
+	// GitHubSourceURL if empty, tarballs then having no links.
+	SourceURL string
+	// IndexURL is the URL of the version index, VersionIndexURL if empty
+	IndexURL string
+	// IndexCache is the file where version index is written on each fetch
+	// and read when it can not be fetched, not cached if empty
+	IndexCache string
+	// HistoryURL is the URL of the release history, ReleaseHistoryURL if
+	// empty
+	HistoryURL string
+	// Client sends HTTP requests, a client honoring proxy environment
+	// variables if nil
+	Client *http.Client
+	// Retries is the number of times a download failing with a network or
+	// server error is attempted again, from scratch
+	Retries int
+	// Timeout is the maximum duration of HTTP requests, no timeout if zero
+	Timeout time.Duration
+	// SkipVerify disables verification of downloaded archives against
+	// checksums of the version index
+	SkipVerify bool
+	// Resume keeps archives partially downloaded in cache directory, in
+	// .part files, and resumes their download with range requests
+	Resume bool
+	// Strict makes errors parsing source files fail the version, instead
+	// of skipping these files
+	Strict bool
+	// Workers is the number of goroutines parsing files of a version, the
+	// number of CPUs if zero
+	Workers int
+	// Logger prints progress messages, nothing is printed if nil
+	Logger *Logger
+	// version index, fetched once for checksums and latest versions
+	indexOnce sync.Once
+	releases  []Release
+	indexErr  error
+	// files parsed and skipped by version, as versions may be processed in
+	// parallel
+	parsedFiles map[string]int
+	fileErrors  map[string][]FileError
+	filesMutex  sync.Mutex
+}
+
+// FileError is an error parsing a source file
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// Error returns the message of the error
+func (f FileError) Error() string {
+	return fmt.Sprintf("could not parse %s: %v", f.Path, f.Err)
+}
+
+// Unwrap returns the parsing error
+func (f FileError) Unwrap() error {
+	return f.Err
+}
+
+// IndexError is an error fetching or reading the version index
+type IndexError struct {
+	Err error
+}
+
+// Error returns the message of the error
+func (i IndexError) Error() string {
+	return fmt.Sprintf("could not fetch version index: %v", i.Err)
+}
+
+// Unwrap returns the fetching error
+func (i IndexError) Unwrap() error {
+	return i.Err
+}
+
+// FileErrors returns errors of source files skipped for given version by
+// the last extraction, sorted by path
+func (e *Extractor) FileErrors(version string) []FileError {
+	e.filesMutex.Lock()
+	defer e.filesMutex.Unlock()
+	return e.fileErrors[version]
+}
+
+// ParsedFiles returns the number of source files parsed for given version
+// by the last extraction, with or without interfaces, as recorded in cached
+// results
+func (e *Extractor) ParsedFiles(version string) int {
+	e.filesMutex.Lock()
+	defer e.filesMutex.Unlock()
+	return e.parsedFiles[version]
+}
+
+// setFiles records the number of source files parsed and errors of source
+// files skipped for given version
+func (e *Extractor) setFiles(version string, parsed int, fileErrors []FileError) {
+	sort.Slice(fileErrors, func(i, j int) bool { return fileErrors[i].Path < fileErrors[j].Path })
+	e.filesMutex.Lock()
+	defer e.filesMutex.Unlock()
+	if e.fileErrors == nil {
+		e.parsedFiles = make(map[string]int)
+		e.fileErrors = make(map[string][]FileError)
+	}
+	e.parsedFiles[version] = parsed
+	e.fileErrors[version] = fileErrors
+}
+
 // Interface is an interface
 type Interface struct {
-	Name    string
-	Package string
+	Name    string `json:"name"`
+	Package string `json:"package"`
+}
+
+// QualifiedName returns name of interface with its package, such as
+// io.Reader, or its name alone in the root package of sources
+func (i Interface) QualifiedName() string {
+	if i.Package == "" {
+		return i.Name
+	}
+	return i.Package + "." + i.Name
+}
+
+// Location is the location in sources
+type Location struct {
+	SourceFile string `json:"sourceFile"`
+	LineNumber string `json:"lineNumber"`
+	// Column and Offset locate the name of the declaration, as LineNumber,
+	// with column starting at 1 and offset in bytes. They are only set by
+	// the AST parser.
+	Column  int      `json:"column,omitempty"`
+	Offset  int      `json:"offset,omitempty"`
+	Link    string   `json:"link"`
+	Methods []Method `json:"methods,omitempty"`
+	// MethodCount is the number of methods, counting embedded interfaces as
+	// one method unless resolved
+	MethodCount int `json:"methodCount"`
+	// Embeds are the names of embedded interfaces, such as Reader or io.Reader
+	Embeds []string `json:"embeds,omitempty"`
+	// EmbedLinks are links to embedded interfaces, by name, when known
+	EmbedLinks map[string]string `json:"embedLinks,omitempty"`
+	// TypeParams is the type parameter list of generic interfaces
+	TypeParams string `json:"typeParams,omitempty"`
+	// IsConstraint tells if interface declares a type set and thus may only
+	// be used as a type constraint
+	IsConstraint bool `json:"isConstraint,omitempty"`
+	// Kind tells if interface declares methods, a type set or both, and
+	// Constraint is its type set, such as ~int | ~int64, with elements on
+	// several lines separated with semicolons. They are only set by the AST
+	// parser.
+	Kind       InterfaceKind `json:"kind,omitempty"`
+	Constraint string        `json:"constraint,omitempty"`
+	// BuildTags is the build constraint of the source file, such as unix ||
+	// windows, from its go:build line or +build lines in older releases.
+	// It is only set by the AST parser.
+	BuildTags string `json:"buildTags,omitempty"`
+	// Exported tells if interface is exported
+	Exported bool `json:"exported"`
+	// Scope is the function declaring a local interface, such as
+	// Reader.Read, empty for interfaces declared at package level
+	Scope string `json:"scope,omitempty"`
+	// Doc is the doc comment of the declaration, if captured
+	Doc string `json:"doc,omitempty"`
+	// Alias tells if the declaration is an alias of the Target interface,
+	// such as io.Reader, with its methods
+	Alias  bool   `json:"alias,omitempty"`
+	Target string `json:"target,omitempty"`
+	// Alternates are other declarations of the interface in the same
+	// version, such as in platform specific files
+	Alternates []Location `json:"alternates,omitempty"`
+}
+
+// Method is a method of an interface or an embedded interface
+type Method struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	Embedded  bool   `json:"embedded,omitempty"`
+}
+
+// String returns the signature of the method, marked if embedded
+func (m Method) String() string {
+	if m.Embedded {
+		return m.Signature + " (embedded)"
+	}
+	return m.Signature
+}
+
+// Row is an interface with its location for a given version. A JSON array
+// of rows is the schema of snapshots, read back with ReadSnapshot: fields
+// may be added but are never renamed nor removed.
+type Row struct {
+	Interface
+	Version string `json:"version"`
+	// ReleaseDate is the release date of the version, such as 2024-02-06,
+	// if known
+	ReleaseDate string `json:"releaseDate,omitempty"`
+	Location
+}
+
+// InterfaceList is a map of interfaces to their location
+type InterfaceList map[Interface]map[string]Location
+
+// NewInterfaceList builds a list of interfaces
+func NewInterfaceList() InterfaceList {
+	return make(map[Interface]map[string]Location)
+}
+
+// AddInterfaces adds interfaces found for given version to the list
+func (il InterfaceList) AddInterfaces(version string, interfaces map[Interface]Location) {
+	for interf, location := range interfaces {
+		if il[interf] == nil {
+			il[interf] = make(map[string]Location)
+		}
+		il[interf][version] = location
+	}
+}
+
+// LatestVersion returns the last of given versions declaring an interface,
+// empty if none declares it
+func (il InterfaceList) LatestVersion(interf Interface, versions []string) string {
+	for v := len(versions) - 1; v >= 0; v-- {
+		if _, ok := il[interf][versions[v]]; ok {
+			return versions[v]
+		}
+	}
+	return ""
+}
+
+// Latest returns location of an interface in the last of given versions
+// declaring it
+func (il InterfaceList) Latest(interf Interface, versions []string) Location {
+	return il[interf][il.LatestVersion(interf, versions)]
+}
+
+// ResolveAliases keeps aliases of interfaces declared in the same version,
+// with target set to the package and name of the interface, and its methods.
+// Aliases of other types are dropped.
+func (il InterfaceList) ResolveAliases() {
+	for interf, locations := range il {
+		for version, location := range locations {
+			if !location.Alias {
+				continue
+			}
+			target, ok := il.resolveAlias(location.Target, interf.Package, version)
+			if !ok {
+				delete(locations, version)
+				continue
+			}
+			declaration := il[target][version]
+			location.Target = target.QualifiedName()
+			location.Methods = declaration.Methods
+			location.MethodCount = declaration.MethodCount
+			location.Embeds = declaration.Embeds
+			location.IsConstraint = declaration.IsConstraint
+			location.Kind = declaration.Kind
+			location.Constraint = declaration.Constraint
+			locations[version] = location
+		}
+		if len(locations) == 0 {
+			delete(il, interf)
+		}
+	}
+}
+
+// resolveAlias returns the interface aliased by name from a package in given
+// version, following aliases of aliases
+func (il InterfaceList) resolveAlias(name, pkg, version string) (Interface, bool) {
+	for depth := 0; depth < 10; depth++ {
+		// ignore type arguments of generic interfaces
+		if index := strings.Index(name, "["); index >= 0 {
+			name = name[:index]
+		}
+		target, ok := il.lookup(name, pkg, version)
+		if !ok {
+			return Interface{}, false
+		}
+		location := il[target][version]
+		if !location.Alias {
+			return target, true
+		}
+		name, pkg = location.Target, target.Package
+	}
+	return Interface{}, false
+}
+
+// LinkEmbeds sets links to embedded interfaces declared in the list, in the
+// same version. This must be done before filtering the list.
+func (il InterfaceList) LinkEmbeds() {
+	for interf, locations := range il {
+		for version, location := range locations {
+			links := make(map[string]string)
+			for _, embed := range location.Embeds {
+				// ignore type arguments of generic interfaces
+				name := embed
+				if index := strings.Index(name, "["); index >= 0 {
+					name = name[:index]
+				}
+				if embedded, ok := il.lookup(name, interf.Package, version); ok {
+					links[embed] = il[embedded][version].Link
+				}
+			}
+			if len(links) > 0 {
+				location.EmbedLinks = links
+				locations[version] = location
+			}
+		}
+	}
+}
+
+// ResolveEmbedded sets method counts of interfaces, counting methods of
+// embedded interfaces declared in the same version instead of one method
+// per embedded interface
+func (il InterfaceList) ResolveEmbedded() {
+	counts := make(map[Interface]map[string]int)
+	for interf, locations := range il {
+		counts[interf] = make(map[string]int)
+		for version := range locations {
+			counts[interf][version] = il.countMethods(interf, version, make(map[Interface]bool))
+		}
+	}
+	for interf, locations := range il {
+		for version, location := range locations {
+			location.MethodCount = counts[interf][version]
+			locations[version] = location
+		}
+	}
+}
+
+// countMethods counts methods of an interface in a version, recursively
+// counting methods of embedded interfaces, visited are interfaces already
+// counted to avoid cycles
+func (il InterfaceList) countMethods(interf Interface, version string, visited map[Interface]bool) int {
+	visited[interf] = true
+	count := 0
+	for _, method := range il[interf][version].Methods {
+		if !method.Embedded {
+			count++
+			continue
+		}
+		embedded, ok := il.lookup(method.Name, interf.Package, version)
+		if !ok || visited[embedded] {
+			count++
+			continue
+		}
+		count += il.countMethods(embedded, version, visited)
+	}
+	return count
+}
+
+// lookup finds an interface referenced by name, such as Reader or
+// io.Reader, from a package in given version. Qualified names are resolved
+// against last element of package paths.
+func (il InterfaceList) lookup(name, pkg, version string) (Interface, bool) {
+	if !strings.Contains(name, ".") {
+		interf := Interface{Name: name, Package: pkg}
+		_, ok := il[interf][version]
+		return interf, ok
+	}
+	qualifier := name[:strings.Index(name, ".")]
+	name = name[strings.Index(name, ".")+1:]
+	candidates := make([]Interface, 0)
+	for interf, locations := range il {
+		if _, ok := locations[version]; ok && interf.Name == name &&
+			(interf.Package == qualifier || strings.HasSuffix(interf.Package, "/"+qualifier)) {
+			candidates = append(candidates, interf)
+		}
+	}
+	if len(candidates) == 0 {
+		return Interface{}, false
+	}
+	// prefer shortest package path, such as io over internal/io
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i].Package) != len(candidates[j].Package) {
+			return len(candidates[i].Package) < len(candidates[j].Package)
+		}
+		return candidates[i].Package < candidates[j].Package
+	})
+	return candidates[0], true
+}
+
+// Filter returns a list with locations of interfaces for which keep returns
+// true, interfaces without remaining location are dropped
+func (il InterfaceList) Filter(keep func(interf Interface, location Location) bool) InterfaceList {
+	filtered := NewInterfaceList()
+	for interf, locations := range il {
+		for version, location := range locations {
+			if keep(interf, location) {
+				if filtered[interf] == nil {
+					filtered[interf] = make(map[string]Location)
+				}
+				filtered[interf][version] = location
+			}
+		}
+	}
+	return filtered
+}
+
+// Version returns a list with interfaces of given version only
+func (il InterfaceList) Version(version string) InterfaceList {
+	list := NewInterfaceList()
+	for interf, locations := range il {
+		if location, ok := locations[version]; ok {
+			list[interf] = map[string]Location{version: location}
+		}
+	}
+	return list
+}
+
+// Rows returns interfaces of the list for given versions, sorted by package,
+// name and version order
+func (il InterfaceList) Rows(versions []string) []Row {
+	rows := make([]Row, 0)
+	for interf, locations := range il {
+		for _, version := range versions {
+			if location, ok := locations[version]; ok {
+				rows = append(rows, Row{Interface: interf, Version: version, Location: location})
+			}
+		}
+	}
+	sortRows(rows, versions)
+	return rows
+}
+
+// ReadSnapshot reads a JSON array of rows, such as printed by the command
+// with JSON format, and returns their list with their versions in release
+// order. Unknown fields, from other releases of the command, are ignored.
+func ReadSnapshot(reader io.Reader) (InterfaceList, []string, error) {
+	var rows []Row
+	if err := json.NewDecoder(reader).Decode(&rows); err != nil {
+		return nil, nil, fmt.Errorf("could not read snapshot: %v", err)
+	}
+	list := NewInterfaceList()
+	versions := make([]string, 0)
+	for _, row := range rows {
+		if row.Name == "" || row.Version == "" {
+			return nil, nil, fmt.Errorf("could not read snapshot: row without name or version")
+		}
+		if list[row.Interface] == nil {
+			list[row.Interface] = make(map[string]Location)
+		}
+		if !containsString(versions, row.Version) {
+			versions = append(versions, row.Version)
+		}
+		list[row.Interface][row.Version] = row.Location
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versionLess(versions[i], versions[j]) })
+	return list, versions, nil
+}
+
+// containsString tells if a slice contains a string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRows sorts rows by package, name and version order
+func sortRows(rows []Row, versions []string) {
+	order := make(map[string]int)
+	for i, version := range versions {
+		order[version] = i
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Package != rows[j].Package {
+			return rows[i].Package < rows[j].Package
+		}
+		if rows[i].Name != rows[j].Name {
+			return rows[i].Name < rows[j].Name
+		}
+		return order[rows[i].Version] < order[rows[j].Version]
+	})
+}
+
+// Introduction is the first version declaring an interface
+type Introduction struct {
+	Interface
+	IntroducedIn string `json:"introducedIn"`
+	// ReleaseDate is the release date of IntroducedIn version, if known
+	ReleaseDate string `json:"releaseDate,omitempty"`
+	Location
+}
+
+// Introductions returns the first of given versions declaring each
+// interface of the list, sorted by version, package and name
+func (il InterfaceList) Introductions(versions []string) []Introduction {
+	sorted := append([]string(nil), versions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return versionLess(sorted[i], sorted[j]) })
+	order := make(map[string]int)
+	for i, version := range sorted {
+		order[version] = i
+	}
+	introductions := make([]Introduction, 0, len(il))
+	for interf, locations := range il {
+		for _, version := range sorted {
+			if location, ok := locations[version]; ok {
+				introductions = append(introductions, Introduction{Interface: interf, IntroducedIn: version, Location: location})
+				break
+			}
+		}
+	}
+	sort.Slice(introductions, func(i, j int) bool {
+		a, b := introductions[i], introductions[j]
+		if a.IntroducedIn != b.IntroducedIn {
+			return order[a.IntroducedIn] < order[b.IntroducedIn]
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Name < b.Name
+	})
+	return introductions
+}
+
+// Move is an interface that moved in sources between two versions
+type Move struct {
+	Interface
+	From Location `json:"from"`
+	To   Location `json:"to"`
+}
+
+// Diff is the difference of interfaces between two versions
+type Diff struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Added   []Row  `json:"added"`
+	Removed []Row  `json:"removed"`
+	Moved   []Move `json:"moved"`
+}
+
+// Diff computes the difference of interfaces between two versions of the
+// list. Interfaces are compared by name and package.
+func (il InterfaceList) Diff(from, to string) Diff {
+	diff := Diff{
+		From:    from,
+		To:      to,
+		Added:   make([]Row, 0),
+		Removed: make([]Row, 0),
+		Moved:   make([]Move, 0),
+	}
+	for interf, locations := range il {
+		fromLocation, inFrom := locations[from]
+		toLocation, inTo := locations[to]
+		if inTo && !inFrom {
+			diff.Added = append(diff.Added, Row{Interface: interf, Version: to, Location: toLocation})
+		} else if inFrom && !inTo {
+			diff.Removed = append(diff.Removed, Row{Interface: interf, Version: from, Location: fromLocation})
+		} else if inFrom && inTo && (fromLocation.SourceFile != toLocation.SourceFile ||
+			fromLocation.LineNumber != toLocation.LineNumber) {
+			diff.Moved = append(diff.Moved, Move{Interface: interf, From: fromLocation, To: toLocation})
+		}
+	}
+	sortRows(diff.Added, nil)
+	sortRows(diff.Removed, nil)
+	sort.Slice(diff.Moved, func(i, j int) bool {
+		if diff.Moved[i].Package != diff.Moved[j].Package {
+			return diff.Moved[i].Package < diff.Moved[j].Package
+		}
+		return diff.Moved[i].Name < diff.Moved[j].Name
+	})
+	return diff
+}
+
+// PackageComparison compares interfaces of two packages in a version
+type PackageComparison struct {
+	Version    string `json:"version"`
+	First      string `json:"first"`
+	Second     string `json:"second"`
+	OnlyFirst  []Row  `json:"onlyFirst"`
+	OnlySecond []Row  `json:"onlySecond"`
+	// Both are names of interfaces declared in both packages
+	Both []string `json:"both"`
+}
+
+// ComparePackages compares interfaces of packages first and second in given
+// version of the list. Interfaces are compared by name.
+func (il InterfaceList) ComparePackages(first, second, version string) PackageComparison {
+	comparison := PackageComparison{
+		Version:    version,
+		First:      first,
+		Second:     second,
+		OnlyFirst:  make([]Row, 0),
+		OnlySecond: make([]Row, 0),
+		Both:       make([]string, 0),
+	}
+	for interf, locations := range il {
+		location, ok := locations[version]
+		if !ok {
+			continue
+		}
+		row := Row{Interface: interf, Version: version, Location: location}
+		switch interf.Package {
+		case first:
+			if _, ok := il[Interface{Name: interf.Name, Package: second}][version]; ok {
+				comparison.Both = append(comparison.Both, interf.Name)
+			} else {
+				comparison.OnlyFirst = append(comparison.OnlyFirst, row)
+			}
+		case second:
+			if _, ok := il[Interface{Name: interf.Name, Package: first}][version]; !ok {
+				comparison.OnlySecond = append(comparison.OnlySecond, row)
+			}
+		}
+	}
+	sortRows(comparison.OnlyFirst, nil)
+	sortRows(comparison.OnlySecond, nil)
+	sort.Strings(comparison.Both)
+	return comparison
+}
+
+// MethodChange is the change of methods of an interface between two
+// versions
+type MethodChange struct {
+	Interface
+	Added   []Method `json:"added"`
+	Removed []Method `json:"removed"`
+}
+
+// MethodDiff computes changes of methods of interfaces declared in both
+// versions of the list, sorted by package and name. Methods are compared by
+// signature, thus a method which signature changed is removed and added.
+func (il InterfaceList) MethodDiff(from, to string) []MethodChange {
+	changes := make([]MethodChange, 0)
+	for interf, locations := range il {
+		fromLocation, inFrom := locations[from]
+		toLocation, inTo := locations[to]
+		if !inFrom || !inTo {
+			continue
+		}
+		change := MethodChange{
+			Interface: interf,
+			Added:     missingMethods(toLocation.Methods, fromLocation.Methods),
+			Removed:   missingMethods(fromLocation.Methods, toLocation.Methods),
+		}
+		if len(change.Added) > 0 || len(change.Removed) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Package != changes[j].Package {
+			return changes[i].Package < changes[j].Package
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// missingMethods returns methods which are not in others
+func missingMethods(methods, others []Method) []Method {
+	signatures := make(map[string]bool)
+	for _, other := range others {
+		signatures[other.String()] = true
+	}
+	missing := make([]Method, 0)
+	for _, method := range methods {
+		if !signatures[method.String()] {
+			missing = append(missing, method)
+		}
+	}
+	return missing
+}
+
+// Orders to sort interfaces
+const (
+	SortName    = "name"
+	SortPackage = "package"
+	SortFile    = "file"
+	SortLine    = "line"
+	SortMethods = "methods"
+)
+
+// Less tells if interface a at location locA is before interface b at
+// location locB in given order. Methods order puts interfaces with more
+// methods first, ties being broken by package and name. Ties are broken by
+// name, package, source file and line, so that the order is total.
+func Less(order string, a Interface, locA Location, b Interface, locB Location) bool {
+	lineA, _ := strconv.Atoi(locA.LineNumber)
+	lineB, _ := strconv.Atoi(locB.LineNumber)
+	switch order {
+	case SortPackage:
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+	case SortFile:
+		if locA.SourceFile != locB.SourceFile {
+			return locA.SourceFile < locB.SourceFile
+		}
+	case SortLine:
+		if lineA != lineB {
+			return lineA < lineB
+		}
+	case SortMethods:
+		if locA.MethodCount != locB.MethodCount {
+			return locA.MethodCount > locB.MethodCount
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	if a.Package != b.Package {
+		return a.Package < b.Package
+	}
+	if locA.SourceFile != locB.SourceFile {
+		return locA.SourceFile < locB.SourceFile
+	}
+	return lineA < lineB
+}
+
+// Sorted returns interfaces of the list sorted in given order, using their
+// location in the last of versions declaring them
+func (il InterfaceList) Sorted(versions []string, order string) []Interface {
+	interfaces := make([]Interface, 0, len(il))
+	locations := make(map[Interface]Location)
+	for i := range il {
+		interfaces = append(interfaces, i)
+		locations[i] = il.Latest(i, versions)
+	}
+	sort.Sort(ByName(interfaces))
+	sort.SliceStable(interfaces, func(i, j int) bool {
+		return Less(order, interfaces[i], locations[interfaces[i]], interfaces[j], locations[interfaces[j]])
+	})
+	return interfaces
+}
+
+// Top returns a list of the first count interfaces of the list sorted in
+// given order, with all their versions
+func (il InterfaceList) Top(versions []string, order string, count int) InterfaceList {
+	top := NewInterfaceList()
+	for _, interf := range il.Sorted(versions, order) {
+		if len(top) >= count {
+			break
+		}
+		top[interf] = il[interf]
+	}
+	return top
+}
+
+// ByName is a list of interfaces
+type ByName []Interface
+
+// Len returns the length of the list
+func (b ByName) Len() int { return len(b) }
+
+// Swap swaps two interfaces
+func (b ByName) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+
+// Less tells if i is less than j, by name then package so that the order
+// does not depend on map iteration
+func (b ByName) Less(i, j int) bool {
+	if b[i].Name != b[j].Name {
+		return b[i].Name < b[j].Name
+	}
+	return b[i].Package < b[j].Package
+}
+
+// parseVersion returns major, minor, patch, stage (0 for beta, 1 for
+// release candidate and 2 for release) and prerelease numbers of given
+// version, such as 1.21.5, 1.21rc1 or 1.22beta1
+func parseVersion(v string) ([5]int, error) {
+	matches := regexp.MustCompile(versionRegexp).FindStringSubmatch(v)
+	if matches == nil {
+		return [5]int{}, fmt.Errorf("malformed version %q", v)
+	}
+	var key [5]int
+	key[0], _ = strconv.Atoi(matches[1])
+	key[1], _ = strconv.Atoi(matches[2])
+	key[2], _ = strconv.Atoi(matches[3])
+	switch matches[4] {
+	case "beta":
+		key[3] = 0
+	case "rc":
+		key[3] = 1
+	default:
+		key[3] = 2
+	}
+	key[4], _ = strconv.Atoi(matches[5])
+	return key, nil
+}
+
+// majMin returns major and minor numbers of given version
+func majMin(v string) (int, int, error) {
+	key, err := parseVersion(v)
+	if err != nil {
+		return 0, 0, err
+	}
+	return key[0], key[1], nil
+}
+
+// versionLess tells if version a was released before version b, malformed
+// versions come first
+func versionLess(a, b string) bool {
+	keyA, _ := parseVersion(a)
+	keyB, _ := parseVersion(b)
+	for i := range keyA {
+		if keyA[i] != keyB[i] {
+			return keyA[i] < keyB[i]
+		}
+	}
+	return false
+}
+
+// srcDirUrl returns the URL of source directory
+func srcDirURL(v string) (string, string, error) {
+	major, minor, err := majMin(v)
+	if err != nil {
+		return "", "", err
+	}
+	srcDir := ""
+	srcURL := ""
+	if major <= 1 && minor < 4 {
+		srcDir = oldSrcDir
+	} else {
+		srcDir = newSrcDir
+	}
+	if major <= 1 && minor < 2 {
+		srcURL = oldSrcURL
+	} else {
+		srcURL = newSrcURL
+	}
+	return srcDir, srcURL, nil
+}
+
+// Release is a GO release in the version index
+type Release struct {
+	Version string        `json:"version"`
+	Stable  bool          `json:"stable"`
+	Files   []ReleaseFile `json:"files"`
+}
+
+// ReleaseFile is a file of a release in the version index
+type ReleaseFile struct {
+	Filename string `json:"filename"`
+	Kind     string `json:"kind"`
+	SHA256   string `json:"sha256"`
+}
+
+// cancelBody is a response body cancelling its request context when closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context
+func (c cancelBody) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// statusError is an HTTP response with a status other than OK
+type statusError struct {
+	status string
+	code   int
+}
+
+// Error returns the status of the response
+func (s statusError) Error() string {
+	return s.status
+}
+
+// retryable tells if error may not happen on another attempt: network
+// errors, interrupted transfers and server errors
+func retryable(err error) bool {
+	var status statusError
+	if errors.As(err, &status) {
+		return status.code >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retry calls attempt until it succeeds, fails with an error which is not
+// retryable, or retries are exhausted. Delay between attempts doubles each
+// time, with up to 50% jitter.
+func (e *Extractor) retry(ctx context.Context, attempt func() error) error {
+	delay := retryDelay
+	for i := 0; ; i++ {
+		err := attempt()
+		if err == nil || i >= e.Retries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		wait := delay + time.Duration(rand.Int63n(int64(delay/2)+1))
+		e.Logger.Debugf("Retrying in %s after error: %v", wait.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// defaultClient is the HTTP client used by extractors without a client, it
+// sends requests through proxies set in HTTP_PROXY and HTTPS_PROXY
+var defaultClient = &http.Client{Transport: newTransport()}
+
+// newTransport returns a default transport using proxies set in environment
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	return transport
+}
+
+// get sends a GET request to url and returns response body, that must be
+// closed. Request is cancelled with context or after extractor timeout.
+// Responses with a status other than OK are errors.
+func (e *Extractor) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	body, _, err := e.getFrom(ctx, url, 0)
+	return body, err
+}
+
+// getFrom gets content at url from offset, with a range request if offset
+// is positive, and tells if response is partial. Servers which do not
+// support ranges return the whole content.
+func (e *Extractor) getFrom(ctx context.Context, url string, offset int64) (io.ReadCloser, bool, error) {
+	client := e.Client
+	if client == nil {
+		client = defaultClient
+	}
+	cancel := context.CancelFunc(func() {})
+	if e.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, false, err
+	}
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		cancel()
+		return nil, false, err
+	}
+	partial := offset > 0 && response.StatusCode == http.StatusPartialContent
+	if response.StatusCode != http.StatusOK && !partial {
+		response.Body.Close()
+		cancel()
+		return nil, false, statusError{status: response.Status, code: response.StatusCode}
+	}
+	return cancelBody{ReadCloser: response.Body, cancel: cancel}, partial, nil
+}
+
+// fetchReleases fetches all releases in the version index
+func (e *Extractor) fetchReleases(ctx context.Context) ([]Release, error) {
+	url := e.IndexURL
+	if url == "" {
+		url = VersionIndexURL
+	}
+	body, err := e.get(ctx, url)
+	if err != nil {
+		return nil, IndexError{Err: err}
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, IndexError{Err: err}
+	}
+	var releases []Release
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, IndexError{Err: fmt.Errorf("invalid JSON: %v", err)}
+	}
+	if e.IndexCache != "" {
+		if err := writeFile(e.IndexCache, data); err != nil {
+			e.Logger.Errorf("Could not cache version index: %v", err)
+		}
+	}
+	return releases, nil
+}
+
+// cachedReleases returns releases of the version index cached by the last
+// successful fetch
+func (e *Extractor) cachedReleases() ([]Release, error) {
+	data, err := os.ReadFile(e.IndexCache)
+	if err != nil {
+		return nil, err
+	}
+	var releases []Release
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return releases, nil
+}
+
+// ReleaseDates returns release dates of versions in release history, such
+// as 2024-02-06 for 1.22.0
+func (e *Extractor) ReleaseDates(ctx context.Context) (map[string]string, error) {
+	url := e.HistoryURL
+	if url == "" {
+		url = ReleaseHistoryURL
+	}
+	body, err := e.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch release history: %w", err)
+	}
+	defer body.Close()
+	page, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch release history: %w", err)
+	}
+	dates := make(map[string]string)
+	for _, matches := range regexp.MustCompile(releasedRegexp).FindAllStringSubmatch(string(page), -1) {
+		dates[matches[1]] = matches[2]
+	}
+	return dates, nil
+}
+
+// LatestVersions returns the latest release of the n most recent minor
+// versions, oldest first. Betas and release candidates are considered only
+// if prerelease is true.
+func (e *Extractor) LatestVersions(ctx context.Context, n int, prerelease bool) ([]string, error) {
+	versions, err := e.minorReleases(ctx, prerelease)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) > n {
+		versions = versions[len(versions)-n:]
+	}
+	return versions, nil
+}
+
+// VersionsBetween returns the latest release of minor versions from since
+// to until included, oldest first. Until is the most recent minor version
+// if empty. Betas and release candidates are considered only if prerelease
+// is true.
+func (e *Extractor) VersionsBetween(ctx context.Context, since, until string, prerelease bool) ([]string, error) {
+	sinceMajor, sinceMinor, err := majMin(since)
+	if err != nil {
+		return nil, err
+	}
+	untilMajor, untilMinor := -1, -1
+	if until != "" {
+		if untilMajor, untilMinor, err = majMin(until); err != nil {
+			return nil, err
+		}
+	}
+	versions, err := e.minorReleases(ctx, prerelease)
+	if err != nil {
+		return nil, err
+	}
+	between := make([]string, 0, len(versions))
+	for _, version := range versions {
+		major, minor, _ := majMin(version)
+		if major < sinceMajor || (major == sinceMajor && minor < sinceMinor) {
+			continue
+		}
+		if untilMajor >= 0 && (major > untilMajor || (major == untilMajor && minor > untilMinor)) {
+			continue
+		}
+		between = append(between, version)
+	}
+	return between, nil
+}
+
+// PreviousRelease returns the latest stable release of the minor version
+// preceding the one of given version in the version index, such as 1.21.13
+// for 1.22.0
+func (e *Extractor) PreviousRelease(ctx context.Context, version string) (string, error) {
+	major, minor, err := majMin(version)
+	if err != nil {
+		return "", err
+	}
+	versions, err := e.minorReleases(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	previous := ""
+	for _, v := range versions {
+		if vMajor, vMinor, _ := majMin(v); vMajor < major || (vMajor == major && vMinor < minor) {
+			previous = v
+		}
+	}
+	if previous == "" {
+		return "", fmt.Errorf("no release before go%s in version index", version)
+	}
+	return previous, nil
+}
+
+// minorReleases returns the latest release of each minor version in the
+// version index, oldest first
+func (e *Extractor) minorReleases(ctx context.Context, prerelease bool) ([]string, error) {
+	releases, err := e.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[[2]int]string)
+	for _, release := range releases {
+		if !release.Stable && !prerelease {
+			continue
+		}
+		version := strings.TrimPrefix(release.Version, "go")
+		major, minor, err := majMin(version)
+		if err != nil {
+			continue
+		}
+		key := [2]int{major, minor}
+		if current, ok := latest[key]; !ok || versionLess(current, version) {
+			latest[key] = version
+		}
+	}
+	versions := make([]string, 0, len(latest))
+	for _, version := range latest {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versionLess(versions[i], versions[j]) })
+	return versions, nil
+}
+
+// declaration is an interface declaration found in a source file
+type declaration struct {
+	name         string
+	typeParams   string
+	line         int
+	column       int
+	offset       int
+	methods      []Method
+	embeds       []string
+	isConstraint bool
+	kind         InterfaceKind
+	constraint   string
+	buildTags    string
+	doc          string
+	scope        string
+	// target is the aliased type of alias declarations
+	target string
+}
+
+// scanRegexp scans source line by line for type declarations of given kind
+// using regular expressions
+func scanRegexp(source io.Reader, kind string, unexported bool) ([]declaration, error) {
+	name := exportedNameRegexp
+	if unexported {
+		name = anyNameRegexp
+	}
+	regexpInterface := regexp.MustCompile(fmt.Sprintf(interfaceRegexp, name, kindRegexps[kind]))
+	regexpGroupedInterface := regexp.MustCompile(fmt.Sprintf(groupedInterfaceRegexp, name, kindRegexps[kind]))
+	regexpTypeBlockStart := regexp.MustCompile(typeBlockStartRegexp)
+	regexpTypeBlockEnd := regexp.MustCompile(typeBlockEndRegexp)
+	regexpOpeningBrace := regexp.MustCompile(openingBraceRegexp)
+	reader := bufio.NewReader(source)
+	declarations := make([]declaration, 0)
+	// declaration waiting for its opening brace
+	var pending *declaration
+	// tells if we are in a type block and brace depth in this block
+	inTypeBlock := false
+	depth := 0
+	lineNumber := 1
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		// match lines without their ending, LF or CRLF, so that $ matches
+		// their end
+		line = bytes.TrimRight(line, "\r\n")
+		if pending != nil && len(bytes.TrimSpace(line)) > 0 {
+			if regexpOpeningBrace.Match(line) {
+				declarations = append(declarations, *pending)
+			}
+			pending = nil
+		}
+		var matches [][]byte
+		if inTypeBlock {
+			if depth == 0 {
+				if regexpTypeBlockEnd.Match(line) {
+					inTypeBlock = false
+				} else {
+					matches = regexpGroupedInterface.FindSubmatch(line)
+				}
+			}
+			depth += bytes.Count(line, []byte("{")) - bytes.Count(line, []byte("}"))
+		} else if regexpTypeBlockStart.Match(line) {
+			inTypeBlock = true
+			depth = 0
+		} else {
+			matches = regexpInterface.FindSubmatch(line)
+		}
+		if len(matches) > 0 {
+			decl := declaration{name: string(matches[1]), typeParams: string(matches[2]), line: lineNumber}
+			// aliases have no brace to wait for
+			if string(matches[3]) == "{" || kind == KindAlias {
+				declarations = append(declarations, decl)
+			} else {
+				pending = &decl
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		lineNumber++
+	}
+	return declarations, nil
+}
+
+// scanAST parses source and walks its syntax tree for exported type
+// declarations of given kind at package level, and in function bodies if
+// local is true, with their doc comment if docs is true and the build
+// constraint of the file
+func scanAST(filename string, reader io.Reader, kind string, unexported, local, docs, aliases bool) ([]declaration, error) {
+	source, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	fileSet := token.NewFileSet()
+	var mode parser.Mode
+	if docs {
+		mode = parser.ParseComments
+	}
+	file, err := parser.ParseFile(fileSet, filename, source, mode)
+	if err != nil {
+		return nil, err
+	}
+	buildTags := buildConstraint(source)
+	declarations := make([]declaration, 0)
+	// add appends declarations of a type declaration in given scope, the
+	// enclosing function or empty at package level
+	add := func(genDecl *ast.GenDecl, scope string) {
+		if genDecl.Tok != token.TYPE {
+			return
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			// aliases of named types may be aliases of interfaces, which
+			// are only known once all packages are parsed
+			alias := aliases && kind == KindInterface && typeSpec.Assign.IsValid() && isNamed(typeSpec.Type)
+			if (!isKind(typeSpec, kind) && !alias) || (!unexported && !typeSpec.Name.IsExported()) {
+				continue
+			}
+			// line, column and offset are those of the name, in single and
+			// grouped declarations
+			position := fileSet.Position(typeSpec.Name.Pos())
+			// comment of a single declaration is attached to type keyword
+			comment := typeSpec.Doc
+			if comment == nil && !genDecl.Lparen.IsValid() {
+				comment = genDecl.Doc
+			}
+			decl := declaration{
+				name:       typeSpec.Name.Name,
+				typeParams: typeParams(fileSet, typeSpec.TypeParams),
+				line:       position.Line,
+				column:     position.Column,
+				offset:     position.Offset,
+				doc:        strings.TrimSpace(comment.Text()),
+				scope:      scope,
+				buildTags:  buildTags,
+			}
+			if alias {
+				decl.target = types.ExprString(typeSpec.Type)
+			}
+			// methods are only listed for interfaces, not aliases to them
+			if interfaceType, ok := typeSpec.Type.(*ast.InterfaceType); ok && kind == KindInterface {
+				decl.methods = interfaceMethods(fileSet, interfaceType)
+				decl.embeds = embeddedInterfaces(fileSet, interfaceType)
+				decl.isConstraint = isConstraint(interfaceType)
+				decl.constraint = typeSet(fileSet, interfaceType)
+				decl.kind = interfaceKind(interfaceType)
+			}
+			declarations = append(declarations, decl)
+		}
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			add(decl, "")
+		case *ast.FuncDecl:
+			if !local || decl.Body == nil {
+				continue
+			}
+			scope := funcScope(decl)
+			ast.Inspect(decl.Body, func(node ast.Node) bool {
+				if statement, ok := node.(*ast.DeclStmt); ok {
+					add(statement.Decl.(*ast.GenDecl), scope)
+				}
+				return true
+			})
+		}
+	}
+	return declarations, nil
+}
+
+// buildConstraint returns the build constraint of a source file, in line
+// comments before its package clause, such as unix || windows. The go:build
+// line is preferred to +build lines, which are combined, and the constraint
+// is empty if there is none.
+func buildConstraint(source []byte) string {
+	var plusBuild constraint.Expr
+	for _, line := range strings.Split(string(source), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		if constraint.IsGoBuild(line) {
+			if expr, err := constraint.Parse(line); err == nil {
+				return expr.String()
+			}
+			continue
+		}
+		if constraint.IsPlusBuild(line) {
+			expr, err := constraint.Parse(line)
+			switch {
+			case err != nil:
+			case plusBuild == nil:
+				plusBuild = expr
+			default:
+				plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+			}
+		}
+	}
+	if plusBuild == nil {
+		return ""
+	}
+	return plusBuild.String()
+}
+
+// funcScope returns the name of a function, qualified with its receiver
+// type for methods, such as Reader.Read
+func funcScope(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return funcDecl.Name.Name
+	}
+	receiver := funcDecl.Recv.List[0].Type
+	// strip pointer and type parameters of receiver
+	if star, ok := receiver.(*ast.StarExpr); ok {
+		receiver = star.X
+	}
+	switch typ := receiver.(type) {
+	case *ast.IndexExpr:
+		receiver = typ.X
+	case *ast.IndexListExpr:
+		receiver = typ.X
+	}
+	if ident, ok := receiver.(*ast.Ident); ok {
+		return ident.Name + "." + funcDecl.Name.Name
+	}
+	return funcDecl.Name.Name
+}
+
+// isKind tells if type specification declares given kind of type
+func isKind(typeSpec *ast.TypeSpec, kind string) bool {
+	if typeSpec.Assign.IsValid() {
+		return kind == KindAlias
+	}
+	switch typeSpec.Type.(type) {
+	case *ast.InterfaceType:
+		return kind == KindInterface
+	case *ast.StructType:
+		return kind == KindStruct
+	}
+	return false
+}
+
+// isNamed tells if type expression is a named type, such as Reader,
+// io.Reader or Set[T]
+func isNamed(expr ast.Expr) bool {
+	switch typ := expr.(type) {
+	case *ast.Ident:
+		return !predeclaredTypes[typ.Name]
+	case *ast.SelectorExpr:
+		return true
+	case *ast.IndexExpr:
+		return isNamed(typ.X)
+	case *ast.IndexListExpr:
+		return isNamed(typ.X)
+	}
+	return false
+}
+
+// typeParams renders a type parameter list as source, such as [K comparable,
+// V any], empty if there are no type parameters
+func typeParams(fileSet *token.FileSet, params *ast.FieldList) string {
+	if params == nil || len(params.List) == 0 {
+		return ""
+	}
+	fields := make([]string, 0, len(params.List))
+	for _, field := range params.List {
+		names := make([]string, 0, len(field.Names))
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		var constraint bytes.Buffer
+		printer.Fprint(&constraint, fileSet, field.Type)
+		fields = append(fields, strings.Join(names, ", ")+" "+constraint.String())
+	}
+	return "[" + strings.Join(fields, ", ") + "]"
+}
+
+// predeclaredTypes are predeclared types that may appear in type sets
+var predeclaredTypes = map[string]bool{
+	"bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+	"float32": true, "float64": true, "int": true, "int8": true, "int16": true, "int32": true,
+	"int64": true, "rune": true, "string": true, "uint": true, "uint8": true, "uint16": true,
+	"uint32": true, "uint64": true, "uintptr": true,
+}
+
+// isConstraint tells if an interface type declares a type set, with unions,
+// approximations or predeclared types, so that it may only be used as a type
+// constraint
+func isConstraint(interfaceType *ast.InterfaceType) bool {
+	for _, field := range interfaceType.Methods.List {
+		if len(field.Names) == 0 && isTypeElement(field.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTypeElement tells if an embedded element of an interface is a type set
+// element, such as a union, an approximation or a predeclared type, rather
+// than an interface
+func isTypeElement(expr ast.Expr) bool {
+	switch element := expr.(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.ArrayType, *ast.MapType,
+		*ast.ChanType, *ast.FuncType, *ast.StructType, *ast.StarExpr:
+		return true
+	case *ast.Ident:
+		return predeclaredTypes[element.Name]
+	}
+	return false
+}
+
+// typeSet renders type set elements of an interface type as source, such as
+// ~int | ~int64, separated with semicolons, empty if there are none
+func typeSet(fileSet *token.FileSet, interfaceType *ast.InterfaceType) string {
+	elements := make([]string, 0)
+	for _, field := range interfaceType.Methods.List {
+		if len(field.Names) > 0 || !isTypeElement(field.Type) {
+			continue
+		}
+		var source bytes.Buffer
+		printer.Fprint(&source, fileSet, field.Type)
+		elements = append(elements, source.String())
+	}
+	return strings.Join(elements, "; ")
+}
+
+// interfaceKind tells if an interface type declares methods, a type set or
+// both. Interfaces without methods nor type set elements, such as any, and
+// embedding interfaces only, are method interfaces.
+func interfaceKind(interfaceType *ast.InterfaceType) InterfaceKind {
+	methods, elements := false, false
+	for _, field := range interfaceType.Methods.List {
+		switch {
+		case len(field.Names) > 0:
+			methods = true
+		case isTypeElement(field.Type):
+			elements = true
+		}
+	}
+	switch {
+	case elements && methods:
+		return KindMixed
+	case elements:
+		return KindConstraint
+	}
+	return KindMethods
+}
+
+// embeddedInterfaces returns names of interfaces embedded in an interface
+// type, qualified with their package name if declared in another package
+func embeddedInterfaces(fileSet *token.FileSet, interfaceType *ast.InterfaceType) []string {
+	embeds := make([]string, 0)
+	for _, field := range interfaceType.Methods.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+		switch element := field.Type.(type) {
+		case *ast.Ident:
+			if predeclaredTypes[element.Name] {
+				continue
+			}
+		case *ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr:
+		default:
+			continue
+		}
+		var source bytes.Buffer
+		printer.Fprint(&source, fileSet, field.Type)
+		embeds = append(embeds, source.String())
+	}
+	return embeds
+}
+
+// interfaceMethods returns methods and embedded interfaces of an interface
+// type, rendered as source. Type set elements are not methods and are only
+// rendered by typeSet.
+func interfaceMethods(fileSet *token.FileSet, interfaceType *ast.InterfaceType) []Method {
+	methods := make([]Method, 0)
+	for _, field := range interfaceType.Methods.List {
+		if len(field.Names) == 0 && isTypeElement(field.Type) {
+			continue
+		}
+		var source bytes.Buffer
+		printer.Fprint(&source, fileSet, field.Type)
+		if len(field.Names) == 0 {
+			methods = append(methods, Method{
+				Name:      source.String(),
+				Signature: source.String(),
+				Embedded:  true,
+			})
+			continue
+		}
+		for _, name := range field.Names {
+			methods = append(methods, Method{
+				Name:      name.Name,
+				Signature: name.Name + strings.TrimPrefix(source.String(), "func"),
+			})
+		}
+	}
+	return methods
+}
+
+// link returns the link to an interface in style of extractor: to its
+// source, on GitHub unless overridden, or to its documentation on pkg.go.dev
+func (e *Extractor) link(version string, interf Interface, sourceFile, line string) string {
+	if e.LinkStyle == LinkPkgDev {
+		return fmt.Sprintf(docURL, interf.Package, interf.Name)
+	}
+	sourceURL := e.SourceURL
+	if sourceURL == "" {
+		sourceURL = GitHubSourceURL
+	}
+	return fmt.Sprintf(sourceURL, releaseTag(version), sourceFile, line)
+}
+
+// releaseTag returns the tag of a release in GO repository, such as go1.21.0,
+// go1.20 or go1.22rc1, with a single go prefix if version already has one.
+// First release of GO 1 is tagged go1 and not go1.0.
+func releaseTag(version string) string {
+	version = strings.TrimPrefix(version, "go")
+	if version == "1" || version == "1.0" {
+		return "go1"
+	}
+	return "go" + version
+}
+
+// treeLink returns the link to an interface in sources of tree. Releases
+// are linked as by link, other sources only with a source URL, with version
+// as is and not as a release tag.
+func (e *Extractor) treeLink(tree sourceTree, version string, interf Interface, sourceFile, line string) string {
+	if tree.release {
+		return e.link(version, interf, sourceFile, line)
+	}
+	if e.SourceURL == "" || e.LinkStyle == LinkPkgDev {
+		return ""
+	}
+	return fmt.Sprintf(e.SourceURL, version, sourceFile, line)
+}
+
+// sourceTree locates packages in paths of source files, in archives or
+// named as in archives
+type sourceTree struct {
+	// prefix is the directory holding packages, such as go/src/ in GO
+	// archives, with a trailing slash unless empty
+	prefix string
+	// root is removed from paths to get source files, such as go/ in GO
+	// archives so that they are relative to the repository
+	root string
+	// release tells if sources are a GO release, where commands, vendored
+	// and internal packages at top level are skipped, linked on GitHub by
+	// default
+	release bool
+}
+
+// releaseTree returns the tree of a GO release with packages in source dir,
+// such as src or src/pkg before GO 1.4. Source dir may be passed with
+// slashes, such as src/.
+func releaseTree(sourceDir string) sourceTree {
+	prefix := archiveRoot
+	if sourceDir = strings.Trim(sourceDir, "/"); sourceDir != "" {
+		prefix += sourceDir + "/"
+	}
+	return sourceTree{prefix: prefix, root: archiveRoot, release: true}
+}
+
+// sourcePackage returns the package of file with given name in tree and
+// tells if it is to be parsed. Package is the directory relative to prefix
+// of tree, empty for the root package of files directly in it.
+func (e *Extractor) sourcePackage(filename string, tree sourceTree) (string, bool) {
+	if !strings.HasPrefix(filename, tree.prefix) {
+		return "", false
+	}
+	pack := path.Dir(filename[len(tree.prefix):])
+	if pack == "." {
+		pack = ""
+	}
+	if hasSegment(pack, "testdata") {
+		return "", false
+	}
+	// directories passed with -include-dir are parsed even if excluded from
+	// releases, such as cmd/...
+	included := len(e.IncludeDirs) > 0 && inDirs(pack, e.IncludeDirs)
+	if tree.release && releaseExcluded[strings.SplitN(pack, "/", 2)[0]] && !included {
+		return "", false
+	}
+	if (e.ExcludeInternal && hasSegment(pack, "internal")) || (e.ExcludeVendor && hasSegment(pack, "vendor")) {
+		return "", false
+	}
+	if (len(e.IncludeDirs) > 0 && !included) || inDirs(pack, e.ExcludeDirs) {
+		return "", false
+	}
+	return pack, true
+}
+
+// releaseExcluded are top level directories of GO releases which are not
+// parsed unless included, as they hold commands and packages not in the
+// standard library API
+var releaseExcluded = map[string]bool{"cmd": true, "vendor": true, "internal": true}
+
+// inDirs tells if package is one of directories or in one of them, such as
+// net/http in net or net/...
+func inDirs(pack string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = strings.Trim(strings.TrimSuffix(dir, "..."), "/")
+		if dir == "" || pack == dir || strings.HasPrefix(pack, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSource parses a source file with default options and returns its
+// interfaces
+func ParseSource(filename string, source io.Reader, sourceDir, version string) (map[Interface]Location, error) {
+	return (&Extractor{}).ParseSource(filename, source, sourceDir, version)
+}
+
+// ParseSource parses a source file and returns its interfaces. Filename is
+// its path in source archives, such as go/src/io/io.go with source dir src,
+// files outside of packages of source dir are ignored.
+func (e *Extractor) ParseSource(filename string, source io.Reader, sourceDir, version string) (map[Interface]Location, error) {
+	interfaces := make(map[Interface]Location)
+	if err := e.ParseSourceFile(filename, source, sourceDir, version, interfaces); err != nil {
+		return nil, err
+	}
+	return interfaces, nil
+}
+
+// ParseSourceFile parses a source file of a GO release and populates the
+// interface map
+func (e *Extractor) ParseSourceFile(filename string, source io.Reader, sourceDir string, version string, interfaces map[Interface]Location) error {
+	return e.parseSourceFile(filename, source, releaseTree(sourceDir), version, interfaces)
+}
+
+// parseSourceFile parses a source file of tree and populates the interface
+// map
+func (e *Extractor) parseSourceFile(filename string, source io.Reader, tree sourceTree, version string, interfaces map[Interface]Location) error {
+	pack, ok := e.sourcePackage(filename, tree)
+	if !ok {
+		return nil
+	}
+	kind := e.Kind
+	if kind == "" {
+		kind = KindInterface
+	}
+	var declarations []declaration
+	var err error
+	if e.Parser == ParserRegexp {
+		declarations, err = scanRegexp(source, kind, e.IncludeUnexported)
+	} else {
+		declarations, err = scanAST(filename, source, kind, e.IncludeUnexported, e.IncludeLocal, e.Doc != "", e.IncludeAliases)
+	}
+	if err != nil {
+		return FileError{Path: filename, Err: err}
+	}
+	e.Logger.Logf(LevelDebug, Fields{"version": version, "file": filename, "count": len(declarations)},
+		"Parsed %s: %d interfaces", filename, len(declarations))
+	for _, decl := range declarations {
+		interf := Interface{
+			Name:    decl.name,
+			Package: pack,
+		}
+		// local interfaces are named after their scope, as names may be
+		// declared in several functions
+		if decl.scope != "" {
+			interf.Name = decl.scope + "." + decl.name
+		}
+		// source file is relative to root of tree, as in links
+		sourceFile := strings.TrimPrefix(filename, tree.root)
+		line := strconv.Itoa(decl.line)
+		location := Location{
+			SourceFile:   sourceFile,
+			LineNumber:   line,
+			Column:       decl.column,
+			Offset:       decl.offset,
+			Link:         e.treeLink(tree, version, interf, sourceFile, line),
+			Methods:      decl.methods,
+			MethodCount:  len(decl.methods),
+			Embeds:       decl.embeds,
+			TypeParams:   decl.typeParams,
+			IsConstraint: decl.isConstraint,
+			Kind:         decl.kind,
+			Constraint:   decl.constraint,
+			Exported:     decl.scope == "" && token.IsExported(decl.name),
+			Scope:        decl.scope,
+			Doc:          decl.doc,
+			Alias:        decl.target != "",
+			Target:       decl.target,
+			BuildTags:    decl.buildTags,
+		}
+		if e.Doc == DocShort {
+			location.Doc = doc.Synopsis(decl.doc)
+		}
+		if other, ok := interfaces[interf]; ok {
+			location = e.merge(other, location)
+		}
+		interfaces[interf] = location
+	}
+	return nil
+}
+
+// sourceFile is a source file read in memory, to be parsed by a worker
+type sourceFile struct {
+	name   string
+	source []byte
+	tree   sourceTree
+}
+
+// parseFiles parses source files sent by read with Workers goroutines and
+// returns interfaces found. Read runs in the calling goroutine, as archives
+// must be read sequentially, and files are parsed while it reads next ones.
+// Files which could not be parsed are skipped and recorded, unless strict.
+func (e *Extractor) parseFiles(version string, read func(files chan<- sourceFile) error) (map[Interface]Location, error) {
+	workers := e.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	files := make(chan sourceFile, workers)
+	// each worker populates its own map, merged afterwards
+	found := make([]map[Interface]Location, workers)
+	errs := make([]error, workers)
+	skipped := make([][]FileError, workers)
+	parsed := make([]int, workers)
+	var group sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		found[w] = make(map[Interface]Location)
+		group.Add(1)
+		go func(w int) {
+			defer group.Done()
+			// files are drained after an error, so that read is not blocked
+			for file := range files {
+				if errs[w] != nil {
+					continue
+				}
+				err := e.parseSourceFile(file.name, bytes.NewReader(file.source), file.tree, version, found[w])
+				var fileError FileError
+				if err != nil && !e.Strict && errors.As(err, &fileError) {
+					skipped[w] = append(skipped[w], fileError)
+					continue
+				}
+				if err == nil {
+					parsed[w]++
+				}
+				errs[w] = err
+			}
+		}(w)
+	}
+	err := read(files)
+	close(files)
+	group.Wait()
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	count := 0
+	fileErrors := make([]FileError, 0)
+	for w, s := range skipped {
+		count += parsed[w]
+		fileErrors = append(fileErrors, s...)
+	}
+	e.setFiles(version, count, fileErrors)
+	// canonical locations do not depend on the order of merges
+	interfaces := found[0]
+	for _, other := range found[1:] {
+		for interf, location := range other {
+			if existing, ok := interfaces[interf]; ok {
+				location = e.merge(existing, location)
+			}
+			interfaces[interf] = location
+		}
+	}
+	return interfaces, nil
+}
+
+// merge returns the canonical location of two declarations of the same
+// interface, with the other one as alternate if all locations are kept.
+// The result does not depend on the order of arguments, thus on the order
+// files are parsed in.
+func (e *Extractor) merge(a, b Location) Location {
+	alternates := append(a.Alternates, b.Alternates...)
+	a.Alternates, b.Alternates = nil, nil
+	if canonicalLess(b, a) {
+		a, b = b, a
+	}
+	e.Logger.Debugf("Interface declared in %s and %s, keeping %s", a.SourceFile, b.SourceFile, a.SourceFile)
+	if e.AllLocations {
+		a.Alternates = append(alternates, b)
+		sort.Slice(a.Alternates, func(i, j int) bool { return canonicalLess(a.Alternates[i], a.Alternates[j]) })
+	}
+	return a
+}
+
+// platformFile matches source files for a given platform
+var platformFile = regexp.MustCompile(platformFileRegexp)
+
+// canonicalLess tells if location a is preferred to location b: files
+// without platform suffix come first, then by path and lowest line
+func canonicalLess(a, b Location) bool {
+	platformA := platformFile.MatchString(a.SourceFile)
+	platformB := platformFile.MatchString(b.SourceFile)
+	if platformA != platformB {
+		return !platformA
+	}
+	if a.SourceFile != b.SourceFile {
+		return a.SourceFile < b.SourceFile
+	}
+	lineA, _ := strconv.Atoi(a.LineNumber)
+	lineB, _ := strconv.Atoi(b.LineNumber)
+	return lineA < lineB
+}
+
+// hasSegment tells if package path contains given segment
+func hasSegment(pack, segment string) bool {
+	for _, s := range strings.Split(pack, "/") {
+		if s == segment {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveName returns the name of the source archive for given version
+func archiveName(version string) string {
+	return "go" + version + ".src.tar.gz"
+}
+
+// resultName returns the name of the file caching interfaces of given
+// version
+func resultName(version string) string {
+	return "go" + version + ".interfaces.json"
+}
+
+// ResultStamp is changed when parsing changes results, so that cached
+// interfaces are parsed again
+const ResultStamp = "5"
+
+// cachedResult is the content of files caching interfaces of a version,
+// with the stamp of parser and options which found them and the number of
+// source files parsed
+type cachedResult struct {
+	Stamp      string `json:"stamp"`
+	Files      int    `json:"files"`
+	Interfaces []Row  `json:"interfaces"`
+}
+
+// resultKey returns the stamp of interfaces found with extractor options
+func (e *Extractor) resultKey() string {
+	parser, kind, style := e.Parser, e.Kind, e.LinkStyle
+	if parser == "" {
+		parser = ParserAST
+	}
+	if kind == "" {
+		kind = KindInterface
+	}
+	if style == "" {
+		style = LinkGitHub
+	}
+	if style == LinkGitHub && e.SourceURL != "" && e.SourceURL != GitHubSourceURL {
+		style += " source=" + e.SourceURL
+	}
+	return fmt.Sprintf("%s parser=%s kind=%s link=%s doc=%s unexported=%t local=%t aliases=%t internal=%t vendor=%t all=%t include=%s exclude=%s",
+		ResultStamp, parser, kind, style, e.Doc, e.IncludeUnexported, e.IncludeLocal, e.IncludeAliases, !e.ExcludeInternal, !e.ExcludeVendor, e.AllLocations,
+		strings.Join(e.IncludeDirs, ","), strings.Join(e.ExcludeDirs, ","))
+}
+
+// loadResult returns cached interfaces of given version and tells if they
+// were found with current parser and options
+func (e *Extractor) loadResult(version string) (map[Interface]Location, bool) {
+	if e.CacheDir == "" || e.Refresh {
+		return nil, false
+	}
+	path := filepath.Join(e.CacheDir, resultName(version))
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+	var cached cachedResult
+	if err := json.NewDecoder(file).Decode(&cached); err != nil {
+		e.Logger.Debugf("Ignoring cached interfaces %s: %v", path, err)
+		return nil, false
+	}
+	if cached.Stamp != e.resultKey() {
+		e.Logger.Debugf("Ignoring cached interfaces %s found with other parser or options", path)
+		return nil, false
+	}
+	interfaces := make(map[Interface]Location, len(cached.Interfaces))
+	for _, row := range cached.Interfaces {
+		interfaces[row.Interface] = row.Location
+	}
+	e.setFiles(version, cached.Files, nil)
+	e.Logger.Debugf("Using cached interfaces %s", path)
+	return interfaces, true
+}
+
+// saveResult caches interfaces of given version, errors are only logged as
+// interfaces may be parsed again. Versions with skipped files are not cached,
+// so that these files are reported on each run.
+func (e *Extractor) saveResult(version string, interfaces map[Interface]Location) {
+	if e.CacheDir == "" || len(e.FileErrors(version)) > 0 {
+		return
+	}
+	cached := cachedResult{Stamp: e.resultKey(), Files: e.ParsedFiles(version), Interfaces: make([]Row, 0, len(interfaces))}
+	for interf, location := range interfaces {
+		cached.Interfaces = append(cached.Interfaces, Row{Interface: interf, Version: version, Location: location})
+	}
+	sortRows(cached.Interfaces, []string{version})
+	data, err := json.Marshal(cached)
+	if err != nil {
+		e.Logger.Errorf("Could not cache interfaces of go%s: %v", version, err)
+		return
+	}
+	if err := writeFile(filepath.Join(e.CacheDir, resultName(version)), data); err != nil {
+		e.Logger.Errorf("Could not cache interfaces of go%s: %v", version, err)
+	}
+}
+
+// writeFile writes data in a temporary file moved to path, as for archives,
+// so that a partial file is never read
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// DefaultCacheDir returns the default directory for cached archives, in
+// $XDG_CACHE_HOME or ~/.cache
+func DefaultCacheDir() string {
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		cacheHome = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheHome, "gointerfaces")
+}
+
+// CheckFilenameTemplate tells if text is a valid template of names of
+// source archives
+func CheckFilenameTemplate(text string) error {
+	_, err := filename(text, "1.22.0")
+	return err
+}
+
+// filename returns name of source archive of version with template text
+func filename(text, version string) (string, error) {
+	tmpl, err := template.New("filename").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid filename template: %v", err)
+	}
+	var name bytes.Buffer
+	if err := tmpl.Execute(&name, struct{ Version string }{version}); err != nil {
+		return "", fmt.Errorf("invalid filename template: %v", err)
+	}
+	if name.Len() == 0 {
+		return "", fmt.Errorf("invalid filename template: empty name")
+	}
+	return name.String(), nil
+}
+
+// archiveFile returns the name of source archive for given version after
+// base URL, with filename template
+func (e *Extractor) archiveFile(version string) (string, error) {
+	text := e.FilenameTemplate
+	if text == "" {
+		text = DefaultFilenameTemplate
+	}
+	return filename(text, version)
+}
+
+// archiveURL returns URL of source archive for given version, on mirror if
+// any, named after filename template
+func (e *Extractor) archiveURL(version string) (string, error) {
+	_, srcURL, err := srcDirURL(version)
+	if err != nil {
+		return "", err
+	}
+	if e.Mirror != "" {
+		srcURL = strings.TrimSuffix(e.Mirror, "/") + "/"
+	}
+	name, err := e.archiveFile(version)
+	if err != nil {
+		return "", err
+	}
+	return srcURL + name, nil
 }
 
-// Location is the location in sources
-type Location struct {
-	SourceFile string
-	LineNumber string
-	Link       string
+// cachePath returns the path of source archive for given version in cache
+// directory. It is named after filename template, with slashes replaced by
+// underscores, so that archives of other templates are not taken for it.
+func (e *Extractor) cachePath(version string) (string, error) {
+	name, err := e.archiveFile(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(e.CacheDir, strings.ReplaceAll(name, "/", "_")), nil
 }
 
-// InterfaceList is a map of interfaces to their location
-type InterfaceList map[Interface]map[string]Location
+// Plan tells what extraction of a version would download and read
+type Plan struct {
+	Version string `json:"version"`
+	// URL is the URL of source archive
+	URL string `json:"url"`
+	// CachePath is the path of archive in cache, empty without cache
+	CachePath string `json:"cachePath,omitempty"`
+	// Cached tells if archive is in cache and thus not downloaded
+	Cached bool `json:"cached"`
+	// ResultCached tells if interfaces found with the same options are in
+	// cache and thus archive is not read
+	ResultCached bool `json:"resultCached"`
+	// Links is the format of links to sources or documentation
+	Links string `json:"links"`
+}
 
-// NewInterfaceList builds a list of interfaces
-func NewInterfaceList() InterfaceList {
-	return make(map[Interface]map[string]Location)
+// Plan returns what extraction of given version would download and read,
+// without network access
+func (e *Extractor) Plan(version string) (Plan, error) {
+	url, err := e.archiveURL(version)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{Version: version, URL: url, Links: docURL}
+	if e.LinkStyle != LinkPkgDev {
+		plan.Links = e.link(version, Interface{}, "%s", "%s")
+	}
+	if e.CacheDir != "" {
+		if plan.CachePath, err = e.cachePath(version); err != nil {
+			return Plan{}, err
+		}
+		if _, err := os.Stat(plan.CachePath); err == nil {
+			plan.Cached = true
+		}
+		_, plan.ResultCached = e.loadResult(version)
+	}
+	return plan, nil
 }
 
-// AddInterface adds an interface to a list
-func (il InterfaceList) AddInterface(name, pkg, version, sourceFile, lineNumber string) {
-	interf := Interface{
-		Name:    name,
-		Package: pkg,
+// download downloads source archive for given version at url
+func (e *Extractor) download(ctx context.Context, version, url string) (io.ReadCloser, error) {
+	e.Logger.Debugf("Downloading %s", url)
+	body, err := e.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch go%s: %w", version, err)
 	}
-	link := fmt.Sprintf(sourceURL, version, sourceFile, lineNumber)
-	location := Location{
-		SourceFile: sourceFile,
-		LineNumber: lineNumber,
-		Link:       link,
+	return body, nil
+}
+
+// tempArchive is a temporary archive file removed when closed
+type tempArchive struct {
+	*os.File
+}
+
+// Close closes and removes the temporary file
+func (t tempArchive) Close() error {
+	err := t.File.Close()
+	os.Remove(t.Name())
+	return err
+}
+
+// index returns releases in the version index, fetched on first call. If
+// it can not be fetched, the cached index is used if any.
+func (e *Extractor) index(ctx context.Context) ([]Release, error) {
+	e.indexOnce.Do(func() {
+		e.releases, e.indexErr = e.fetchReleases(ctx)
+		if e.indexErr == nil || e.IndexCache == "" || ctx.Err() != nil {
+			return
+		}
+		releases, err := e.cachedReleases()
+		if err != nil {
+			e.Logger.Debugf("No cached version index: %v", err)
+			return
+		}
+		e.Logger.Errorf("%v, using cached index %s", e.indexErr, e.IndexCache)
+		e.releases, e.indexErr = releases, nil
+	})
+	return e.releases, e.indexErr
+}
+
+// ResetIndex forgets the version index, to fetch it again on next use. It
+// must not be called while extracting interfaces.
+func (e *Extractor) ResetIndex() {
+	e.indexOnce = sync.Once{}
+	e.releases, e.indexErr = nil, nil
+}
+
+// checksum returns the SHA-256 checksum of source archive for given version
+// in the version index, empty if archive is not listed in the index
+func (e *Extractor) checksum(ctx context.Context, version string) (string, error) {
+	releases, err := e.index(ctx)
+	if err != nil {
+		return "", err
 	}
-	if il[interf] == nil {
-		il[interf] = make(map[string]Location)
+	for _, release := range releases {
+		for _, file := range release.Files {
+			if file.Filename == archiveName(version) {
+				return file.SHA256, nil
+			}
+		}
 	}
-	il[interf][version] = location
+	return "", nil
 }
 
-// ByName is a list of interfaces
-type ByName []Interface
+// openArchive returns a reader on source archive for given version. If a
+// cache directory is set, archive is read from cache, or downloaded and
+// saved in cache if not found there, named after filename template. Unless
+// verification is skipped, downloaded archive is checked against checksum in
+// version index.
+func (e *Extractor) openArchive(ctx context.Context, version, url string) (io.ReadCloser, error) {
+	path := ""
+	if e.CacheDir != "" {
+		var err error
+		if path, err = e.cachePath(version); err != nil {
+			return nil, err
+		}
+		if file, err := os.Open(path); err == nil {
+			e.Logger.Debugf("Using cached archive %s", path)
+			return file, nil
+		}
+	}
+	checksum := ""
+	if !e.SkipVerify {
+		var err error
+		checksum, err = e.checksum(ctx, version)
+		if err != nil {
+			return nil, fmt.Errorf("could not verify go%s: %v (use -skip-verify to skip verification)", version, err)
+		}
+		if checksum == "" {
+			e.Logger.Infof("No checksum for go%s in version index, skipping verification", version)
+		}
+	}
+	if path == "" && checksum == "" {
+		return e.download(ctx, version, url)
+	}
+	if e.Resume && path != "" {
+		return e.resumeDownload(ctx, version, url, path, checksum)
+	}
+	body, err := e.download(ctx, version, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	// write archive in a temporary file, moved to cache when download is
+	// complete and verified
+	tempDir := e.CacheDir
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	} else if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %v", err)
+	}
+	temp, err := os.CreateTemp(tempDir, archiveName(version)+".*.part")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary file: %v", err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(temp, hash), body); err != nil {
+		tempArchive{temp}.Close()
+		return nil, fmt.Errorf("could not fetch go%s: %w", version, err)
+	}
+	if checksum != "" && hex.EncodeToString(hash.Sum(nil)) != checksum {
+		tempArchive{temp}.Close()
+		return nil, fmt.Errorf("bad checksum for go%s archive", version)
+	}
+	if path == "" {
+		if _, err := temp.Seek(0, io.SeekStart); err != nil {
+			tempArchive{temp}.Close()
+			return nil, err
+		}
+		return tempArchive{temp}, nil
+	}
+	defer os.Remove(temp.Name())
+	if err := temp.Close(); err != nil {
+		return nil, fmt.Errorf("could not write cache file: %v", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return nil, fmt.Errorf("could not write cache file: %v", err)
+	}
+	return os.Open(path)
+}
 
-// Len returns the length of the list
-func (b ByName) Len() int { return len(b) }
+// resumeDownload downloads archive in a .part file next to path, resuming
+// download of a previous attempt, and moves it to path once complete and
+// verified. Part file is kept on network errors, to resume on next attempt,
+// and removed if checksum is wrong.
+func (e *Extractor) resumeDownload(ctx context.Context, version, url, path, checksum string) (io.ReadCloser, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %v", err)
+	}
+	part := path + ".part"
+	file, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary file: %v", err)
+	}
+	defer file.Close()
+	// hash downloaded part, which leaves file at its end
+	hash := sha256.New()
+	offset, err := io.Copy(hash, file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", part, err)
+	}
+	e.Logger.Debugf("Downloading %s", url)
+	body, partial, err := e.getFrom(ctx, url, offset)
+	var status statusError
+	if errors.As(err, &status) && status.code == http.StatusRequestedRangeNotSatisfiable {
+		body, partial, err = e.getFrom(ctx, url, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch go%s: %w", version, err)
+	}
+	defer body.Close()
+	if offset > 0 && partial {
+		e.Logger.Debugf("Resuming download of go%s after %d bytes", version, offset)
+	} else if offset > 0 {
+		e.Logger.Debugf("Server does not support ranges, downloading go%s again", version)
+		if err := file.Truncate(0); err != nil {
+			return nil, fmt.Errorf("could not write %s: %v", part, err)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("could not write %s: %v", part, err)
+		}
+		hash.Reset()
+	}
+	if _, err := io.Copy(io.MultiWriter(file, hash), body); err != nil {
+		return nil, fmt.Errorf("could not fetch go%s: %w", version, err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("could not write cache file: %v", err)
+	}
+	if checksum != "" && hex.EncodeToString(hash.Sum(nil)) != checksum {
+		os.Remove(part)
+		return nil, fmt.Errorf("bad checksum for go%s archive", version)
+	}
+	if err := os.Rename(part, path); err != nil {
+		return nil, fmt.Errorf("could not write cache file: %v", err)
+	}
+	return os.Open(path)
+}
 
-// Swap swaps two interfaces
-func (b ByName) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+// isSourceFile tells if file with given name is a source file to parse in
+// tree, test data is ignored
+func isSourceFile(name string, tree sourceTree) bool {
+	return strings.HasPrefix(name, tree.prefix) &&
+		!strings.Contains(name, "/testdata/") &&
+		strings.HasSuffix(name, ".go") &&
+		!strings.HasSuffix(name, "doc.go") &&
+		!strings.HasSuffix(name, "_test.go")
+}
 
-// Less tells if i is less than j
-func (b ByName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+// readSourceFile reads file with given name in memory and sends it to files,
+// if it is a source file to parse
+func (e *Extractor) readSourceFile(name string, reader io.Reader, tree sourceTree, files chan<- sourceFile) error {
+	if !isSourceFile(name, tree) {
+		return nil
+	}
+	if _, ok := e.sourcePackage(name, tree); !ok {
+		return nil
+	}
+	source, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	files <- sourceFile{name: name, source: source, tree: tree}
+	return nil
+}
 
-// srcDirUrl returns the URL of source directory
-func srcDirURL(v string) (string, string) {
-	array := strings.Split(strings.Split(strings.Split(v, "beta")[0], "rc")[0], ".")
-	major, err := strconv.Atoi(array[0])
+// InterfacesForVersion returns interfaces for given version
+func InterfacesForVersion(ctx context.Context, version string) (map[Interface]Location, error) {
+	return (&Extractor{}).InterfacesForVersion(ctx, version)
+}
+
+// InterfacesForVersion returns interfaces for given version
+func (e *Extractor) InterfacesForVersion(ctx context.Context, version string) (map[Interface]Location, error) {
+	e.Logger.Logf(LevelInfo, Fields{"version": version}, "Generating interface list for version %s...", version)
+	srcDir, _, err := srcDirURL(version)
 	if err != nil {
-		major = 0
+		return nil, err
+	}
+	if interfaces, ok := e.loadResult(version); ok {
+		return interfaces, nil
+	}
+	if e.Extract {
+		dir, err := e.ExtractVersion(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		defer e.RemoveExtracted(dir)
+		interfaces, err := e.walkDirectory(ctx, filepath.Join(dir, "go", filepath.FromSlash(srcDir)), srcDir, version)
+		if err != nil {
+			return nil, err
+		}
+		e.saveResult(version, interfaces)
+		return interfaces, nil
 	}
-	minor, err := strconv.Atoi(array[1])
+	// parse tar source files in source dir, from scratch on each attempt
+	var interfaces map[Interface]Location
+	err = e.retry(ctx, func() error {
+		var err error
+		interfaces, err = e.parseFiles(version, func(files chan<- sourceFile) error {
+			return e.readArchive(ctx, version, func(header *tar.Header, reader io.Reader) error {
+				return e.readSourceFile(header.Name, reader, releaseTree(srcDir), files)
+			})
+		})
+		return err
+	})
 	if err != nil {
-		minor = 0
+		return nil, err
 	}
-	srcDir := ""
-	srcURL := ""
-	if major <= 1 && minor < 4 {
-		srcDir = oldSrcDir
-	} else {
-		srcDir = newSrcDir
+	e.logPackages(version, interfaces)
+	e.saveResult(version, interfaces)
+	return interfaces, nil
+}
+
+// readArchive calls handle on each entry of source archive for given
+// version, from cache or network
+func (e *Extractor) readArchive(ctx context.Context, version string, handle func(header *tar.Header, reader io.Reader) error) error {
+	url, err := e.archiveURL(version)
+	if err != nil {
+		return err
 	}
-	if major <= 1 && minor < 2 {
-		srcURL = oldSrcURL
-	} else {
-		srcURL = newSrcURL
+	// open compressed archive, from cache or network
+	archive, err := e.openArchive(ctx, version, url)
+	if err != nil {
+		return err
 	}
-	return srcDir, srcURL
+	defer archive.Close()
+	return e.readTar(ctx, "go"+version+" archive", archive, handle)
 }
 
-// parseSourceFile parses a source file and populates the interface list
-func parseSourceFile(filename string, source io.Reader, sourceDir string, version string, interfaces InterfaceList) {
-	regexpInterface := regexp.MustCompile(interfaceRegexp)
-	reader := bufio.NewReader(source)
-	pack := filename[len(sourceDir)+4 : strings.LastIndex(filename, "/")]
-	if strings.HasSuffix(pack, "testdata") || strings.HasPrefix(pack, "cmd") ||
-		strings.HasPrefix(pack, "vendor") || strings.HasPrefix(pack, "internal") {
-		return
+// readTar calls handle on each entry of a tar archive, compressed with gzip
+// or not, which name is used in messages
+func (e *Extractor) readTar(ctx context.Context, name string, archive io.Reader, handle func(header *tar.Header, reader io.Reader) error) error {
+	counter := &countingReader{reader: archive}
+	buffered := bufio.NewReader(counter)
+	var reader io.Reader = buffered
+	// gunzip the archive stream, if starting with gzip magic number
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", name, err)
+		}
+		reader = gzipReader
+	} else {
+		e.Logger.Debugf("Reading %s as an uncompressed tar archive", name)
 	}
-	lineNumber := 1
+	tarReader := tar.NewReader(reader)
 	for {
-		line, err := reader.ReadBytes('\n')
-		if err != nil && err != io.EOF {
-			panic("Error parsing source file")
-		}
-		matches := regexpInterface.FindSubmatch(line)
-		if len(matches) > 0 {
-			name := string(matches[1])
-			pkg := string(pack)
-			sourceFile := filename[3:]
-			lineNumber := strconv.Itoa(lineNumber)
-			interfaces.AddInterface(name, pkg, version, sourceFile, lineNumber)
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("could not read %s: %w", name, err)
 		}
+		header, err := tarReader.Next()
 		if err == io.EOF {
 			break
 		}
-		lineNumber++
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", name, err)
+		}
+		if err := handle(header, tarReader); err != nil {
+			return fmt.Errorf("could not read %s: %w", name, err)
+		}
+	}
+	// tar reading stops at its end marker, gzip checksum and size are
+	// checked at the end of stream so that a truncated trailer is an error
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("could not read %s: %w", name, err)
+	}
+	e.Logger.Debugf("Read %d bytes of %s", counter.count, name)
+	return nil
+}
+
+// InterfacesForTarball returns interfaces in GO sources of any compressed
+// tar archive at given URL, labeled with given version. Prefix is the
+// directory of the archive holding packages, as go/src in GO archives,
+// such as project-1.0 or project-1.0/src. Source files are paths in the
+// archive, linked only with a source URL, and all packages are parsed,
+// including commands and internal ones. Archive is neither cached nor
+// verified.
+func (e *Extractor) InterfacesForTarball(ctx context.Context, url, prefix, version string) (map[Interface]Location, error) {
+	return e.InterfacesForTarballPrefixes(ctx, url, []string{prefix}, version)
+}
+
+// InterfacesForTarballPrefixes returns interfaces in GO sources of any
+// compressed tar archive at given URL as InterfacesForTarball, with packages
+// in several directories of the archive. Packages are named after the
+// longest prefix of their path.
+func (e *Extractor) InterfacesForTarballPrefixes(ctx context.Context, url string, prefixes []string, version string) (map[Interface]Location, error) {
+	e.Logger.Infof("Generating interface list for archive %s...", url)
+	trees := make([]sourceTree, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if prefix = strings.Trim(prefix, "/"); prefix != "" {
+			prefix += "/"
+		}
+		trees = append(trees, sourceTree{prefix: prefix})
+	}
+	if len(trees) == 0 {
+		trees = append(trees, sourceTree{})
+	}
+	// longest prefixes first, so that nested ones match first
+	sort.Slice(trees, func(i, j int) bool { return len(trees[i].prefix) > len(trees[j].prefix) })
+	var interfaces map[Interface]Location
+	err := e.retry(ctx, func() error {
+		e.Logger.Debugf("Downloading %s", url)
+		archive, err := e.get(ctx, url)
+		if err != nil {
+			return fmt.Errorf("could not fetch %s: %w", url, err)
+		}
+		defer archive.Close()
+		interfaces, err = e.parseFiles(version, func(files chan<- sourceFile) error {
+			return e.readTar(ctx, url, archive, func(header *tar.Header, reader io.Reader) error {
+				for _, tree := range trees {
+					if strings.HasPrefix(header.Name, tree.prefix) {
+						return e.readSourceFile(header.Name, reader, tree, files)
+					}
+				}
+				return nil
+			})
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	e.logPackages(version, interfaces)
+	return interfaces, nil
 }
 
-// addInterfaces generates interface list for given version
-func addInterfaces(version string, interfaces InterfaceList) {
-	println(fmt.Sprintf("Generating interface list for version %s...", version))
-	srcDir, srcURL := srcDirURL(version)
-	// download compressed archive
-	response, err := http.Get(srcURL + "go" + version + ".src.tar.gz")
+// ExtractVersion extracts source archive for given version in a new
+// temporary directory and returns its path. Sources are in its go
+// subdirectory, as in the archive. Caller should remove the directory.
+func (e *Extractor) ExtractVersion(ctx context.Context, version string) (string, error) {
+	dir, err := os.MkdirTemp("", "gointerfaces-go"+version+"-")
 	if err != nil {
-		panic(err)
+		return "", fmt.Errorf("could not create temporary directory: %v", err)
 	}
-	reader := response.Body
-	defer response.Body.Close()
-	// gunzip the archive stream
-	gzipReader, err := gzip.NewReader(reader)
+	e.Logger.Debugf("Extracting go%s archive in %s", version, dir)
+	// files are overwritten on each attempt
+	err = e.retry(ctx, func() error {
+		return e.readArchive(ctx, version, func(header *tar.Header, reader io.Reader) error {
+			target, err := sanitizeTarPath(dir, header.Name)
+			if err != nil {
+				return err
+			}
+			switch header.Typeflag {
+			case tar.TypeDir:
+				return os.MkdirAll(target, 0755)
+			case tar.TypeReg:
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return err
+				}
+				file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+				if err != nil {
+					return err
+				}
+				if _, err := io.Copy(file, reader); err != nil {
+					file.Close()
+					return err
+				}
+				return file.Close()
+			}
+			// links and special files are not needed to parse sources
+			return nil
+		})
+	})
 	if err != nil {
-		panic(err)
+		os.RemoveAll(dir)
+		return "", err
 	}
-	// parse tar source files in source dir
-	tarReader := tar.NewReader(gzipReader)
-	for {
-		header, err := tarReader.Next()
+	return dir, nil
+}
+
+// sanitizeTarPath returns the path where to write archive entry with given
+// name in dest directory. Absolute names and names escaping dest, with ..
+// components, are rejected. Names of dest itself, such as ./, are accepted.
+func sanitizeTarPath(dest, name string) (string, error) {
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("entry %s has an absolute path", name)
+	}
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if root := filepath.Clean(dest); target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+		return "", fmt.Errorf("entry %s is outside of archive", name)
+	}
+	return target, nil
+}
+
+// RemoveExtracted removes directory of extracted sources, unless they are
+// kept
+func (e *Extractor) RemoveExtracted(dir string) {
+	if e.KeepExtracted {
+		e.Logger.Infof("Kept extracted sources in %s", dir)
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		e.Logger.Errorf("Could not remove %s: %v", dir, err)
+	}
+}
+
+// logPackages prints number of interfaces found per package for a version
+func (e *Extractor) logPackages(version string, interfaces map[Interface]Location) {
+	counts := make(map[string]int)
+	for interf := range interfaces {
+		counts[interf.Package]++
+	}
+	packages := make([]string, 0, len(counts))
+	for pkg := range counts {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	for _, pkg := range packages {
+		e.Logger.Debugf("Found %d interfaces in package %s for version %s", counts[pkg], pkg, version)
+	}
+}
+
+// InterfacesForDirectory returns interfaces in sources of a local directory,
+// such as $GOROOT/src, labeled with given version
+func (e *Extractor) InterfacesForDirectory(ctx context.Context, dir, version string) (map[Interface]Location, error) {
+	e.Logger.Infof("Generating interface list for directory %s...", dir)
+	return e.walkDirectory(ctx, dir, newSrcDir, version)
+}
+
+// walkDirectory returns interfaces in sources of a directory, which is
+// source dir, such as src or src/pkg, in archives
+func (e *Extractor) walkDirectory(ctx context.Context, dir, srcDir, version string) (map[Interface]Location, error) {
+	tree := releaseTree(srcDir)
+	interfaces, err := e.parseFiles(version, func(files chan<- sourceFile) error {
+		return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if entry.Name() == "testdata" {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			relative, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			// name files as in source archives
+			name := archiveRoot + srcDir + "/" + filepath.ToSlash(relative)
+			if !isSourceFile(name, tree) {
+				return nil
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return e.readSourceFile(name, file, tree, files)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %s: %v", dir, err)
+	}
+	e.logPackages(version, interfaces)
+	return interfaces, nil
+}
+
+// Implementer is a named type implementing an interface
+type Implementer struct {
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	// Pointer tells if only the pointer to the type implements interface
+	Pointer    bool   `json:"pointer,omitempty"`
+	SourceFile string `json:"sourceFile"`
+	LineNumber string `json:"lineNumber"`
+	Link       string `json:"link"`
+}
+
+// Implementers type checks packages of GO sources in directory, such as
+// $GOROOT/src, and returns named types implementing the interface with
+// given qualified name, such as io.Reader, sorted by package and name.
+// Packages are loaded from this directory, which must be a complete source
+// tree, without cgo.
+func (e *Extractor) Implementers(ctx context.Context, dir, version, name string) ([]Implementer, error) {
+	dot := strings.LastIndex(name, ".")
+	if dot < 1 {
+		return nil, fmt.Errorf("interface %q must be qualified with its package, such as io.Reader", name)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// packages are found in the source tree and not in the GOROOT of the
+	// process, with a copy of the default build context
+	context := build.Default
+	context.GOROOT = filepath.Dir(dir)
+	context.CgoEnabled = false
+	fileSet := token.NewFileSet()
+	imp := newSourceImporter(context, fileSet)
+	e.Logger.Infof("Type checking packages in %s...", dir)
+	target, err := imp.Import(name[:dot])
+	if err != nil {
+		return nil, fmt.Errorf("could not load package %s: %v", name[:dot], err)
+	}
+	object := target.Scope().Lookup(name[dot+1:])
+	if object == nil {
+		return nil, fmt.Errorf("could not find %s", name)
+	}
+	interf, ok := object.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", name)
+	}
+	implementers := make([]Implementer, 0)
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
-			break
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		pack := filepath.ToSlash(relative)
+		if entry.Name() == "testdata" || pack == "cmd" || pack == "vendor" || pack == "internal" {
+			return fs.SkipDir
+		}
+		if (e.ExcludeInternal && entry.Name() == "internal") || (e.ExcludeVendor && entry.Name() == "vendor") {
+			return fs.SkipDir
+		}
+		if pack == "." {
+			return nil
+		}
+		loaded, err := imp.Import(pack)
+		if err != nil {
+			e.Logger.Debugf("Skipping package %s: %v", pack, err)
+			return nil
+		}
+		scope := loaded.Scope()
+		for _, typeName := range scope.Names() {
+			object, ok := scope.Lookup(typeName).(*types.TypeName)
+			if !ok || !object.Exported() || object.IsAlias() {
+				continue
+			}
+			if _, ok := object.Type().Underlying().(*types.Interface); ok {
+				continue
+			}
+			if named, ok := object.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				continue
+			}
+			pointer := false
+			if !types.Implements(object.Type(), interf) {
+				if !types.Implements(types.NewPointer(object.Type()), interf) {
+					continue
+				}
+				pointer = true
+			}
+			position := fileSet.Position(object.Pos())
+			sourceFile := newSrcDir + "/" + pack + "/" + filepath.Base(position.Filename)
+			line := strconv.Itoa(position.Line)
+			implementers = append(implementers, Implementer{
+				Name:       typeName,
+				Package:    pack,
+				Pointer:    pointer,
+				SourceFile: sourceFile,
+				LineNumber: line,
+				Link:       e.link(version, Interface{Name: typeName, Package: pack}, sourceFile, line),
+			})
 		}
-		if strings.HasPrefix(header.Name, "go/"+srcDir) &&
-			strings.HasSuffix(header.Name, ".go") &&
-			!strings.HasSuffix(header.Name, "doc.go") &&
-			!strings.HasSuffix(header.Name, "_test.go") {
-			parseSourceFile(header.Name, tarReader, srcDir, version, interfaces)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %s: %v", dir, err)
+	}
+	sort.Slice(implementers, func(i, j int) bool {
+		if implementers[i].Package != implementers[j].Package {
+			return implementers[i].Package < implementers[j].Package
 		}
+		return implementers[i].Name < implementers[j].Name
+	})
+	return implementers, nil
+}
+
+// sourceImporter type checks packages from sources found with a build
+// context. The source importer of go/importer only uses the default build
+// context, which is global to the process.
+type sourceImporter struct {
+	context  build.Context
+	fileSet  *token.FileSet
+	sizes    types.Sizes
+	packages map[string]*types.Package
+}
+
+// newSourceImporter returns an importer of sources found with context
+func newSourceImporter(context build.Context, fileSet *token.FileSet) *sourceImporter {
+	return &sourceImporter{
+		context:  context,
+		fileSet:  fileSet,
+		sizes:    types.SizesFor("gc", context.GOARCH),
+		packages: make(map[string]*types.Package),
 	}
 }
 
-// printInterfaces prints interfaces for given versions
-func printInterfaces(interfaceList InterfaceList, versions []string) {
-	interfaces := make([]Interface, 0)
-	for i := range interfaceList {
-		interfaces = append(interfaces, i)
+// Import type checks package with given import path
+func (s *sourceImporter) Import(path string) (*types.Package, error) {
+	return s.ImportFrom(path, ".", 0)
+}
+
+// ImportFrom type checks package with given import path, imported by a
+// package in directory dir, which is used to find vendored packages
+func (s *sourceImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
 	}
-	sort.Sort(ByName(interfaces))
-	lenName := 0
-	lenPackage := 0
-	lenVersions := make(map[string]int)
-	for _, i := range interfaces {
-		if len(i.Name) > lenName {
-			lenName = len(i.Name)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	buildPackage, err := s.context.Import(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := s.packages[buildPackage.ImportPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through package %s", buildPackage.ImportPath)
+		}
+		return pkg, nil
+	}
+	// package being checked is marked to detect import cycles
+	s.packages[buildPackage.ImportPath] = nil
+	files := make([]*ast.File, 0, len(buildPackage.GoFiles))
+	for _, name := range buildPackage.GoFiles {
+		file, err := parser.ParseFile(s.fileSet, filepath.Join(buildPackage.Dir, name), nil, 0)
+		if err != nil {
+			delete(s.packages, buildPackage.ImportPath)
+			return nil, err
 		}
-		if len(i.Package) > lenPackage {
-			lenPackage = len(i.Package)
+		files = append(files, file)
+	}
+	var firstError error
+	config := types.Config{
+		Importer:         s,
+		Sizes:            s.sizes,
+		IgnoreFuncBodies: true,
+		// soft errors, such as unused imports, do not prevent using types
+		Error: func(err error) {
+			if typeError, ok := err.(types.Error); firstError == nil && (!ok || !typeError.Soft) {
+				firstError = err
+			}
+		},
+	}
+	pkg, _ := config.Check(buildPackage.ImportPath, s.fileSet, files, nil)
+	if firstError != nil {
+		delete(s.packages, buildPackage.ImportPath)
+		return nil, fmt.Errorf("type checking package %s: %v", buildPackage.ImportPath, firstError)
+	}
+	s.packages[buildPackage.ImportPath] = pkg
+	return pkg, nil
+}
+
+// PackageCount is the number of interfaces in a package
+type PackageCount struct {
+	Package string `json:"package"`
+	Count   int    `json:"count"`
+}
+
+// CountByPackage returns number of interfaces per package, sorted by count
+// descending then package
+func CountByPackage(interfaces []Interface) []PackageCount {
+	counts := make(map[string]int)
+	for _, interf := range interfaces {
+		counts[interf.Package]++
+	}
+	packageCounts := make([]PackageCount, 0, len(counts))
+	for pkg, count := range counts {
+		packageCounts = append(packageCounts, PackageCount{Package: pkg, Count: count})
+	}
+	sort.Slice(packageCounts, func(i, j int) bool {
+		if packageCounts[i].Count != packageCounts[j].Count {
+			return packageCounts[i].Count > packageCounts[j].Count
 		}
+		return packageCounts[i].Package < packageCounts[j].Package
+	})
+	return packageCounts
+}
+
+// Packages returns packages declaring interfaces in any of versions, with
+// their number of interfaces, sorted by package
+func (il InterfaceList) Packages(versions []string) []PackageCount {
+	interfaces := make([]Interface, 0, len(il))
+	for interf, locations := range il {
 		for _, version := range versions {
-			loc := interfaceList[i][version]
-			lenVersion := len(loc.Link) + 10
-			if lenVersions[version] < lenVersion {
-				lenVersions[version] = lenVersion
+			if _, ok := locations[version]; ok {
+				interfaces = append(interfaces, interf)
+				break
 			}
 		}
 	}
-	formatLine := "%-" + strconv.Itoa(lenName) + "s" + " | %-" + strconv.Itoa(lenPackage) + "s"
-	for _, v := range versions {
-		formatLine += " | %-" + strconv.Itoa(lenVersions[v]) + "s"
+	packages := CountByPackage(interfaces)
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Package < packages[j].Package })
+	return packages
+}
+
+// Normalized returns signature of the method without parameter names and
+// with canonical spacing, such as Read([]byte) (int, error)
+func (m Method) Normalized() string {
+	expr, err := parser.ParseExpr("interface{" + m.Signature + "}")
+	if err != nil {
+		return strings.Join(strings.Fields(m.Signature), " ")
 	}
-	args := []interface{}{"Interface", "Package"}
-	for _, v := range versions {
-		args = append(args, v)
+	interfaceType, ok := expr.(*ast.InterfaceType)
+	if !ok || len(interfaceType.Methods.List) != 1 {
+		return strings.Join(strings.Fields(m.Signature), " ")
 	}
-	fmt.Println(fmt.Sprintf(formatLine, args...))
-	separator := ":" + strings.Repeat("-", lenName-1) + " | :" + strings.Repeat("-", lenPackage-1)
-	for _, v := range versions {
-		separator += " | " + strings.Repeat("-", lenVersions[v])
-	}
-	fmt.Println(separator)
-	for _, i := range interfaces {
-		versionLink := make(map[string]string)
-		for _, v := range versions {
-			if len(interfaceList[i][v].SourceFile) > 0 {
-				versionLink[v] = "[source](" + interfaceList[i][v].Link + ")"
-			} else {
-				versionLink[v] = "-"
-			}
+	field := interfaceType.Methods.List[0]
+	funcType, ok := field.Type.(*ast.FuncType)
+	if !ok || len(field.Names) == 0 {
+		return types.ExprString(field.Type)
+	}
+	funcType.Params = unnamedFields(funcType.Params)
+	funcType.Results = unnamedFields(funcType.Results)
+	return field.Names[0].Name + strings.TrimPrefix(types.ExprString(funcType), "func")
+}
+
+// unnamedFields returns a field per name of fields, without names
+func unnamedFields(fields *ast.FieldList) *ast.FieldList {
+	if fields == nil {
+		return nil
+	}
+	unnamed := &ast.FieldList{}
+	for _, field := range fields.List {
+		for n := 0; n == 0 || n < len(field.Names); n++ {
+			unnamed.List = append(unnamed.List, &ast.Field{Type: field.Type})
 		}
-		args := []interface{}{i.Name, i.Package}
-		for _, v := range versions {
-			args = append(args, versionLink[v])
+	}
+	return unnamed
+}
+
+// MethodSetHash returns a hash of methods and embedded interfaces, which
+// does not depend on their order, parameter names or spacing
+func MethodSetHash(methods []Method) string {
+	signatures := make([]string, len(methods))
+	for m, method := range methods {
+		signatures[m] = method.Normalized()
+	}
+	sort.Strings(signatures)
+	sum := sha256.Sum256([]byte(strings.Join(signatures, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Digest returns the hex encoded SHA-256 of rows of given versions, sorted
+// and encoded in JSON one per line. Release dates, which depend on the
+// availability of the release history, and offsets, which depend on line
+// endings, are left out so that the digest is the same across runs and
+// systems.
+func (il InterfaceList) Digest(versions []string) (string, error) {
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	for _, row := range il.Rows(versions) {
+		row.ReleaseDate = ""
+		row.Offset = 0
+		alternates := make([]Location, len(row.Alternates))
+		for a, alternate := range row.Alternates {
+			alternate.Offset = 0
+			alternates[a] = alternate
+		}
+		row.Alternates = alternates
+		if err := encoder.Encode(row); err != nil {
+			return "", fmt.Errorf("could not compute digest: %v", err)
 		}
-		fmt.Println(fmt.Sprintf(formatLine, args...))
 	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Duplicate is a group of interfaces of several packages with the same
+// method set
+type Duplicate struct {
+	Hash       string   `json:"hash"`
+	Methods    []string `json:"methods"`
+	Interfaces []Row    `json:"interfaces"`
 }
 
-// main is the program entry point
-func main() {
-	// read versions on command line
-	if len(os.Args) < 2 {
-		panic("Must pass go version(s) on command line")
+// Duplicates returns groups of interfaces declared in several packages with
+// the same method set, in the last of versions declaring them. Interfaces
+// without methods are ignored. Groups are sorted by number of interfaces
+// descending then hash, interfaces by package and name.
+func (il InterfaceList) Duplicates(versions []string) []Duplicate {
+	groups := make(map[string]*Duplicate)
+	for _, interf := range il.Sorted(versions, SortPackage) {
+		version := il.LatestVersion(interf, versions)
+		if version == "" {
+			continue
+		}
+		location := il[interf][version]
+		if len(location.Methods) == 0 {
+			continue
+		}
+		hash := MethodSetHash(location.Methods)
+		group, ok := groups[hash]
+		if !ok {
+			methods := make([]string, len(location.Methods))
+			for m, method := range location.Methods {
+				methods[m] = method.Normalized()
+			}
+			sort.Strings(methods)
+			group = &Duplicate{Hash: hash, Methods: methods}
+			groups[hash] = group
+		}
+		group.Interfaces = append(group.Interfaces, Row{Interface: interf, Version: version, Location: location})
+	}
+	duplicates := make([]Duplicate, 0)
+	for _, group := range groups {
+		packages := make(map[string]bool)
+		for _, row := range group.Interfaces {
+			packages[row.Package] = true
+		}
+		if len(packages) > 1 {
+			duplicates = append(duplicates, *group)
+		}
 	}
-	versions := os.Args[1:]
-	// iterate on versions
-	interfaces := NewInterfaceList()
+	sort.Slice(duplicates, func(i, j int) bool {
+		if len(duplicates[i].Interfaces) != len(duplicates[j].Interfaces) {
+			return len(duplicates[i].Interfaces) > len(duplicates[j].Interfaces)
+		}
+		return duplicates[i].Hash < duplicates[j].Hash
+	})
+	return duplicates
+}
+
+// VersionCount is the number of interfaces in a version, with number per
+// package if counted
+type VersionCount struct {
+	Version  string         `json:"version"`
+	Total    int            `json:"total"`
+	Packages []PackageCount `json:"packages,omitempty"`
+}
+
+// Counts returns number of interfaces of the list for given versions, with
+// number per package if perPackage is true
+func (il InterfaceList) Counts(versions []string, perPackage bool) []VersionCount {
+	counts := make([]VersionCount, 0, len(versions))
 	for _, version := range versions {
-		addInterfaces(version, interfaces)
+		interfaces := make([]Interface, 0)
+		for interf, locations := range il {
+			if _, ok := locations[version]; ok {
+				interfaces = append(interfaces, interf)
+			}
+		}
+		count := VersionCount{Version: version, Total: len(interfaces)}
+		if perPackage {
+			count.Packages = CountByPackage(interfaces)
+		}
+		counts = append(counts, count)
 	}
-	// print the result
-	println("Printing table...")
-	printInterfaces(interfaces, versions)
+	return counts
 }
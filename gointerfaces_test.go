@@ -0,0 +1,923 @@
+package gointerfaces
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/c4s4/gointerfaces/internal/testserver"
+)
+
+// parse returns interfaces of a source file of version 1.22.0 parsed by
+// extractor
+func parse(extractor *Extractor, filename, source string) map[Interface]Location {
+	interfaces := make(map[Interface]Location)
+	extractor.ParseSourceFile(filename, strings.NewReader(source), "src", "1.22.0", interfaces)
+	return interfaces
+}
+
+// declared returns lines of interfaces parsed with parser in source of io
+// package, by name
+func declared(parser, source string) map[string]string {
+	interfaces := parse(&Extractor{Parser: parser}, "go/src/io/io.go", source)
+	lines := make(map[string]string, len(interfaces))
+	for interf, location := range interfaces {
+		lines[interf.Name] = location.LineNumber
+	}
+	return lines
+}
+
+func TestScanDeclarations(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected map[string]string
+	}{
+		{
+			name:     "single line",
+			source:   "package io\n\ntype Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n",
+			expected: map[string]string{"Reader": "3"},
+		},
+		{
+			name: "grouped",
+			source: "package io\n\ntype (\n\tReader interface {\n\t\tRead(p []byte) (n int, err error)\n\t}\n\n" +
+				"\tcloser struct{}\n\n\tCloser interface {\n\t\tClose() error\n\t}\n)\n",
+			expected: map[string]string{"Reader": "4", "Closer": "10"},
+		},
+		{
+			name:     "brace on next line",
+			source:   "package io\n\ntype Reader interface\n{\n\tRead(p []byte) (n int, err error)\n}\n",
+			expected: map[string]string{"Reader": "3"},
+		},
+		{
+			name: "CRLF",
+			source: "package io\r\n\r\n// Reader reads.\r\ntype Reader interface {\r\n\tRead(p []byte) (n int, err error)\r\n}\r\n\r\n" +
+				"type (\r\n\tWriter interface {\r\n\t\tWrite(p []byte) (n int, err error)\r\n\t}\r\n)\r\n\r\n" +
+				"type Closer interface\r\n{\r\n\tClose() error\r\n}\r\n",
+			expected: map[string]string{"Reader": "4", "Writer": "9", "Closer": "14"},
+		},
+	}
+	for _, test := range tests {
+		for _, parser := range []string{ParserAST, ParserRegexp} {
+			t.Run(test.name+"/"+parser, func(t *testing.T) {
+				if lines := declared(parser, test.source); !reflect.DeepEqual(lines, test.expected) {
+					t.Errorf("found %v, expected %v", lines, test.expected)
+				}
+			})
+		}
+	}
+}
+
+func TestParseSourceMethods(t *testing.T) {
+	source := "package io\n\n" +
+		"type Closer interface {\n\tClose() error\n}\n\n" +
+		"type ReadWriteCloser interface {\n\tReader\n\tWriter\n\tCloser\n}\n\n" +
+		"type ReadCloser interface {\n\tReader\n\tClose() error\n}\n"
+	tests := []struct {
+		name     string
+		expected []Method
+	}{
+		{
+			name:     "Closer",
+			expected: []Method{{Name: "Close", Signature: "Close() error"}},
+		},
+		{
+			name: "ReadWriteCloser",
+			expected: []Method{
+				{Name: "Reader", Signature: "Reader", Embedded: true},
+				{Name: "Writer", Signature: "Writer", Embedded: true},
+				{Name: "Closer", Signature: "Closer", Embedded: true},
+			},
+		},
+		{
+			name: "ReadCloser",
+			expected: []Method{
+				{Name: "Reader", Signature: "Reader", Embedded: true},
+				{Name: "Close", Signature: "Close() error"},
+			},
+		},
+	}
+	interfaces := parse(&Extractor{}, "go/src/io/io.go", source)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location := interfaces[Interface{Name: test.name, Package: "io"}]
+			if !reflect.DeepEqual(location.Methods, test.expected) {
+				t.Errorf("methods are %+v, expected %+v", location.Methods, test.expected)
+			}
+		})
+	}
+}
+
+// sourceArchive returns a gzipped tar archive of go sources with given
+// contents, by file name, sorted by name
+func sourceArchive(t *testing.T, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, name := range names {
+		content := files[name]
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestReadTarTruncated(t *testing.T) {
+	archive := sourceArchive(t, map[string]string{
+		"go/src/io/io.go": "package io\n\ntype Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n",
+	})
+	tests := []struct {
+		name   string
+		length int
+	}{
+		{name: "complete", length: len(archive)},
+		{name: "header", length: 5},
+		{name: "middle", length: len(archive) / 2},
+		{name: "trailer", length: len(archive) - 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// archive in cache is read instead of downloaded
+			cacheDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(cacheDir, archiveName("1.22.0")), archive[:test.length], 0644); err != nil {
+				t.Fatal(err)
+			}
+			extractor := &Extractor{CacheDir: cacheDir, SkipVerify: true}
+			_, err := extractor.InterfacesForVersion(context.Background(), "1.22.0")
+			if test.length == len(archive) && err != nil {
+				t.Errorf("reading complete archive returned error: %v", err)
+			} else if test.length < len(archive) && err == nil {
+				t.Errorf("reading archive truncated to %d bytes returned no error", test.length)
+			}
+		})
+	}
+}
+
+func TestParseSourceGenerics(t *testing.T) {
+	tests := []struct {
+		name         string
+		filename     string
+		source       string
+		interf       Interface
+		typeParams   string
+		isConstraint bool
+	}{
+		{
+			name:     "cmp.Ordered",
+			filename: "go/src/cmp/cmp.go",
+			source: "package cmp\n\ntype Ordered interface {\n" +
+				"\t~int | ~int8 | ~int16 | ~int32 | ~int64 |\n" +
+				"\t\t~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |\n" +
+				"\t\t~float32 | ~float64 |\n" +
+				"\t\t~string\n}\n",
+			interf:       Interface{Name: "Ordered", Package: "cmp"},
+			isConstraint: true,
+		},
+		{
+			name:       "type parameter",
+			filename:   "go/src/container/container.go",
+			source:     "package container\n\ntype Container[T any] interface {\n\tAdd(value T)\n\tValues() []T\n}\n",
+			interf:     Interface{Name: "Container", Package: "container"},
+			typeParams: "[T any]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location, ok := parse(&Extractor{}, test.filename, test.source)[test.interf]
+			if !ok {
+				t.Fatalf("%s.%s not found", test.interf.Package, test.interf.Name)
+			}
+			if location.TypeParams != test.typeParams {
+				t.Errorf("type parameters are %q, expected %q", location.TypeParams, test.typeParams)
+			}
+			if location.IsConstraint != test.isConstraint {
+				t.Errorf("constraint flag is %t, expected %t", location.IsConstraint, test.isConstraint)
+			}
+		})
+	}
+}
+
+func TestMajMin(t *testing.T) {
+	tests := []struct {
+		version string
+		major   int
+		minor   int
+		err     bool
+	}{
+		{version: "1.21.5", major: 1, minor: 21},
+		{version: "1.21", major: 1, minor: 21},
+		{version: "1.21rc1", major: 1, minor: 21},
+		{version: "1.22beta1", major: 1, minor: 22},
+		{version: "1", major: 1, minor: 0},
+		{version: "1.x", err: true},
+		{version: "1.21-rc1", err: true},
+		{version: "", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			major, minor, err := majMin(test.version)
+			if (err != nil) != test.err {
+				t.Fatalf("majMin(%q) returned error %v", test.version, err)
+			}
+			if major != test.major || minor != test.minor {
+				t.Errorf("majMin(%q) = %d, %d, expected %d, %d", test.version, major, minor, test.major, test.minor)
+			}
+		})
+	}
+}
+
+func TestSrcDirURL(t *testing.T) {
+	tests := []struct {
+		version string
+		srcDir  string
+		srcURL  string
+	}{
+		{version: "1.21rc1", srcDir: "src", srcURL: "https://storage.googleapis.com/golang/"},
+		{version: "1.22beta1", srcDir: "src", srcURL: "https://storage.googleapis.com/golang/"},
+		{version: "1.3", srcDir: "src/pkg", srcURL: "https://storage.googleapis.com/golang/"},
+		{version: "1.1", srcDir: "src/pkg", srcURL: "https://storage.googleapis.com/google-code-archive-downloads/v2/code.google.com/go/"},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			srcDir, srcURL, err := srcDirURL(test.version)
+			if err != nil {
+				t.Fatalf("srcDirURL(%q) returned error: %v", test.version, err)
+			}
+			if srcDir != test.srcDir || srcURL != test.srcURL {
+				t.Errorf("srcDirURL(%q) = %s, %s, expected %s, %s", test.version, srcDir, srcURL, test.srcDir, test.srcURL)
+			}
+		})
+	}
+	if _, _, err := srcDirURL("1.x"); err == nil {
+		t.Errorf("srcDirURL of malformed version returned no error")
+	}
+}
+
+func TestLinkEmbeds(t *testing.T) {
+	sources := map[string]string{
+		"go/src/io/io.go": "package io\n\n" +
+			"type Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n\n" +
+			"type Writer interface {\n\tWrite(p []byte) (n int, err error)\n}\n\n" +
+			"type ReadWriter interface {\n\tReader\n\tWriter\n}\n",
+		"go/src/net/net.go": "package net\n\nimport (\n\t\"fmt\"\n\t\"io\"\n)\n\n" +
+			"type Conn interface {\n\tio.ReadWriter\n\tfmt.Stringer\n\tClose() error\n}\n",
+	}
+	list := NewInterfaceList()
+	for filename, source := range sources {
+		list.AddInterfaces("1.22.0", parse(&Extractor{}, filename, source))
+	}
+	list.LinkEmbeds()
+	tests := []struct {
+		interf Interface
+		embeds []string
+		links  map[string]string
+	}{
+		{
+			interf: Interface{Name: "ReadWriter", Package: "io"},
+			embeds: []string{"Reader", "Writer"},
+			links: map[string]string{
+				"Reader": "https://github.com/golang/go/blob/go1.22.0/src/io/io.go#L3",
+				"Writer": "https://github.com/golang/go/blob/go1.22.0/src/io/io.go#L7",
+			},
+		},
+		{
+			interf: Interface{Name: "Conn", Package: "net"},
+			embeds: []string{"io.ReadWriter", "fmt.Stringer"},
+			links: map[string]string{
+				"io.ReadWriter": "https://github.com/golang/go/blob/go1.22.0/src/io/io.go#L11",
+			},
+		},
+		{
+			interf: Interface{Name: "Reader", Package: "io"},
+			embeds: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.interf.Package+"."+test.interf.Name, func(t *testing.T) {
+			location := list[test.interf]["1.22.0"]
+			if !reflect.DeepEqual(location.Embeds, test.embeds) {
+				t.Errorf("embeds are %q, expected %q", location.Embeds, test.embeds)
+			}
+			if !reflect.DeepEqual(location.EmbedLinks, test.links) {
+				t.Errorf("embed links are %v, expected %v", location.EmbedLinks, test.links)
+			}
+		})
+	}
+}
+
+func TestDuplicateDeclarations(t *testing.T) {
+	sources := []struct {
+		filename string
+		source   string
+	}{
+		{filename: "go/src/net/conn_unix.go", source: "package net\n\ntype Conn interface {\n\tClose() error\n}\n"},
+		{filename: "go/src/net/conn.go", source: "package net\n\n// Conn is a connection.\n\ntype Conn interface {\n\tClose() error\n}\n"},
+	}
+	tests := []struct {
+		name       string
+		all        bool
+		alternates []string
+	}{
+		{name: "canonical"},
+		{name: "all locations", all: true, alternates: []string{"src/net/conn_unix.go"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extractor := &Extractor{AllLocations: test.all}
+			interfaces := make(map[Interface]Location)
+			for _, source := range sources {
+				if err := extractor.ParseSourceFile(source.filename, strings.NewReader(source.source), "src", "1.22.0", interfaces); err != nil {
+					t.Fatalf("ParseSourceFile returned error: %v", err)
+				}
+			}
+			if len(interfaces) != 1 {
+				t.Fatalf("found %d interfaces, expected net.Conn only", len(interfaces))
+			}
+			location := interfaces[Interface{Name: "Conn", Package: "net"}]
+			if location.SourceFile != "src/net/conn.go" || location.LineNumber != "5" {
+				t.Errorf("kept %s:%s, expected src/net/conn.go:5", location.SourceFile, location.LineNumber)
+			}
+			var alternates []string
+			for _, alternate := range location.Alternates {
+				alternates = append(alternates, alternate.SourceFile)
+			}
+			if !reflect.DeepEqual(alternates, test.alternates) {
+				t.Errorf("alternates are %q, expected %q", alternates, test.alternates)
+			}
+		})
+	}
+}
+
+func TestSanitizeTarPath(t *testing.T) {
+	dest := filepath.Join("tmp", "extracted")
+	tests := []struct {
+		name     string
+		expected string
+		err      bool
+	}{
+		{name: "go/src/io/io.go", expected: filepath.Join(dest, "go", "src", "io", "io.go")},
+		{name: "go/src/../VERSION", expected: filepath.Join(dest, "go", "VERSION")},
+		{name: ".", expected: dest},
+		{name: "./", expected: dest},
+		{name: "../../etc/passwd", err: true},
+		{name: "go/../../etc/passwd", err: true},
+		{name: "/etc/passwd", err: true},
+		{name: "..", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target, err := sanitizeTarPath(dest, test.name)
+			if (err != nil) != test.err {
+				t.Fatalf("sanitizeTarPath(%q) returned error %v", test.name, err)
+			}
+			if target != test.expected {
+				t.Errorf("sanitizeTarPath(%q) = %q, expected %q", test.name, target, test.expected)
+			}
+		})
+	}
+}
+
+func TestIncludeUnexported(t *testing.T) {
+	source := "package io\n\ntype Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n\n" +
+		"type reader interface {\n\tread() error\n}\n"
+	tests := []struct {
+		name       string
+		unexported bool
+		expected   map[string]bool
+	}{
+		{name: "default", expected: map[string]bool{"Reader": true}},
+		{name: "unexported", unexported: true, expected: map[string]bool{"Reader": true, "reader": false}},
+	}
+	for _, test := range tests {
+		for _, parser := range []string{ParserAST, ParserRegexp} {
+			t.Run(test.name+"/"+parser, func(t *testing.T) {
+				extractor := &Extractor{Parser: parser, IncludeUnexported: test.unexported}
+				interfaces := parse(extractor, "go/src/io/io.go", source)
+				exported := make(map[string]bool, len(interfaces))
+				for interf, location := range interfaces {
+					exported[interf.Name] = location.Exported
+				}
+				if !reflect.DeepEqual(exported, test.expected) {
+					t.Errorf("found interfaces %v, expected %v", exported, test.expected)
+				}
+			})
+		}
+	}
+}
+
+func TestParseSourceLayouts(t *testing.T) {
+	source := "package io\n\ntype Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n"
+	tests := []struct {
+		version    string
+		filename   string
+		sourceFile string
+		link       string
+	}{
+		{
+			version:    "1.3",
+			filename:   "go/src/pkg/io/io.go",
+			sourceFile: "src/pkg/io/io.go",
+			link:       "https://github.com/golang/go/blob/go1.3/src/pkg/io/io.go#L3",
+		},
+		{
+			version:    "1.4",
+			filename:   "go/src/io/io.go",
+			sourceFile: "src/io/io.go",
+			link:       "https://github.com/golang/go/blob/go1.4/src/io/io.go#L3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			sourceDir, _, err := srcDirURL(test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			interfaces := make(map[Interface]Location)
+			if err := (&Extractor{}).ParseSourceFile(test.filename, strings.NewReader(source), sourceDir, test.version, interfaces); err != nil {
+				t.Fatalf("ParseSourceFile returned error: %v", err)
+			}
+			location, ok := interfaces[Interface{Name: "Reader", Package: "io"}]
+			if !ok {
+				t.Fatalf("io.Reader not found in %v", interfaces)
+			}
+			if location.SourceFile != test.sourceFile {
+				t.Errorf("source file is %s, expected %s", location.SourceFile, test.sourceFile)
+			}
+			if location.Link != test.link {
+				t.Errorf("link is %s, expected %s", location.Link, test.link)
+			}
+			// files directly in source dir are in the root package
+			root := make(map[Interface]Location)
+			if err := (&Extractor{}).ParseSourceFile(archiveRoot+sourceDir+"/io.go", strings.NewReader(source), sourceDir, test.version, root); err != nil {
+				t.Fatalf("ParseSourceFile returned error: %v", err)
+			}
+			if _, ok := root[Interface{Name: "Reader"}]; !ok || len(root) != 1 {
+				t.Errorf("parsing file in source dir found %v, expected Reader in root package", root)
+			}
+		})
+	}
+}
+
+// readEntries returns names of entries of archive read with readTar
+func readEntries(archive []byte) ([]string, error) {
+	names := make([]string, 0)
+	err := (&Extractor{}).readTar(context.Background(), "archive", bytes.NewReader(archive), func(header *tar.Header, reader io.Reader) error {
+		if _, err := io.ReadAll(reader); err != nil {
+			return err
+		}
+		names = append(names, header.Name)
+		return nil
+	})
+	return names, err
+}
+
+func TestReadTarPlain(t *testing.T) {
+	compressed := sourceArchive(t, map[string]string{
+		"go/src/io/io.go":         "package io\n",
+		"go/src/net/net.go":       "package net\n",
+		"go/src/cmd/tool/main.go": "package main\n",
+	})
+	gzipReader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(gzipReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"go/src/cmd/tool/main.go", "go/src/io/io.go", "go/src/net/net.go"}
+	for name, archive := range map[string][]byte{"gzip": compressed, "plain": plain} {
+		t.Run(name, func(t *testing.T) {
+			names, err := readEntries(archive)
+			if err != nil {
+				t.Fatalf("readTar returned error: %v", err)
+			}
+			if !reflect.DeepEqual(names, expected) {
+				t.Errorf("read entries %q, expected %q", names, expected)
+			}
+		})
+	}
+}
+
+func TestParseSourceDoc(t *testing.T) {
+	source := "package io\n\n" +
+		"// Reader is the interface that wraps the basic Read method.\n" +
+		"//\n" +
+		"// Read reads up to len(p) bytes into p.\n" +
+		"type Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n"
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{mode: "", expected: ""},
+		{mode: DocShort, expected: "Reader is the interface that wraps the basic Read method."},
+		{mode: DocFull, expected: "Reader is the interface that wraps the basic Read method.\n\nRead reads up to len(p) bytes into p."},
+	}
+	for _, test := range tests {
+		t.Run("mode "+test.mode, func(t *testing.T) {
+			interfaces := parse(&Extractor{Doc: test.mode}, "go/src/io/io.go", source)
+			if doc := interfaces[Interface{Name: "Reader", Package: "io"}].Doc; doc != test.expected {
+				t.Errorf("doc is %q, expected %q", doc, test.expected)
+			}
+		})
+	}
+}
+
+// goRootArchive returns a tar archive of sources of a few packages of the
+// local GOROOT, named as in source archives
+func goRootArchive(b *testing.B) []byte {
+	b.Helper()
+	root := filepath.Join(runtime.GOROOT(), "src")
+	var archive bytes.Buffer
+	writer := tar.NewWriter(&archive)
+	for _, pack := range []string{"io", "net", "net/http", "go/ast", "go/types", "reflect", "database/sql/driver"} {
+		paths, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(pack), "*.go"))
+		if err != nil || len(paths) == 0 {
+			b.Skipf("no sources of %s in GOROOT", pack)
+		}
+		for _, path := range paths {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			name := "go/src/" + pack + "/" + filepath.Base(path)
+			if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(source))}); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := writer.Write(source); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return archive.Bytes()
+}
+
+func BenchmarkParseFiles(b *testing.B) {
+	archive := goRootArchive(b)
+	// parallel parsing uses a worker per CPU
+	for name, workers := range map[string]int{"sequential": 1, "parallel": 0} {
+		b.Run(name, func(b *testing.B) {
+			extractor := &Extractor{Workers: workers}
+			for i := 0; i < b.N; i++ {
+				_, err := extractor.parseFiles("1.22.0", func(files chan<- sourceFile) error {
+					return extractor.readTar(context.Background(), "archive", bytes.NewReader(archive), func(header *tar.Header, reader io.Reader) error {
+						return extractor.readSourceFile(header.Name, reader, releaseTree("src"), files)
+					})
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLink(t *testing.T) {
+	reader := Interface{Name: "Reader", Package: "io"}
+	tests := []struct {
+		version  string
+		style    string
+		expected string
+	}{
+		{version: "1", expected: "https://github.com/golang/go/blob/go1/src/io/io.go#L86"},
+		{version: "1.0", expected: "https://github.com/golang/go/blob/go1/src/io/io.go#L86"},
+		{version: "1.22", expected: "https://github.com/golang/go/blob/go1.22/src/io/io.go#L86"},
+		{version: "1.22.3", expected: "https://github.com/golang/go/blob/go1.22.3/src/io/io.go#L86"},
+		{version: "1.22rc1", expected: "https://github.com/golang/go/blob/go1.22rc1/src/io/io.go#L86"},
+		{version: "1.21beta1", expected: "https://github.com/golang/go/blob/go1.21beta1/src/io/io.go#L86"},
+		{version: "go1.22.0", expected: "https://github.com/golang/go/blob/go1.22.0/src/io/io.go#L86"},
+		{version: "1.22.0", style: LinkPkgDev, expected: "https://pkg.go.dev/io#Reader"},
+	}
+	for _, test := range tests {
+		t.Run(test.version+test.style, func(t *testing.T) {
+			actual := (&Extractor{LinkStyle: test.style}).link(test.version, reader, "src/io/io.go", "86")
+			if actual != test.expected {
+				t.Errorf("link of %s is %s, expected %s", test.version, actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestTreeLink(t *testing.T) {
+	reader := Interface{Name: "Reader", Package: "foo"}
+	sourceURL := "https://git.example.com/project/blob/v%s/%s#L%s"
+	tests := []struct {
+		name      string
+		tree      sourceTree
+		sourceURL string
+		expected  string
+	}{
+		{name: "release", tree: releaseTree(newSrcDir), expected: "https://github.com/golang/go/blob/go1/project-1.0/foo/foo.go#L3"},
+		{name: "tarball", tree: sourceTree{prefix: "project-1.0/"}, expected: ""},
+		{name: "tarball with source URL", tree: sourceTree{prefix: "project-1.0/"}, sourceURL: sourceURL, expected: "https://git.example.com/project/blob/v1.0/project-1.0/foo/foo.go#L3"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := (&Extractor{SourceURL: test.sourceURL}).treeLink(test.tree, "1.0", reader, "project-1.0/foo/foo.go", "3")
+			if actual != test.expected {
+				t.Errorf("link is %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestIncludeLocal(t *testing.T) {
+	source := "package io\n\ntype Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n\n" +
+		"func Copy() {\n\ttype Flusher interface {\n\t\tFlush() error\n\t}\n\tvar x interface{ Close() error }\n\t_ = x\n}\n"
+	tests := []struct {
+		name     string
+		local    bool
+		expected map[string]string
+	}{
+		{name: "default", expected: map[string]string{"Reader": ""}},
+		{name: "local", local: true, expected: map[string]string{"Reader": "", "Copy.Flusher": "Copy"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interfaces, err := (&Extractor{IncludeLocal: test.local}).ParseSource("go/src/io/io.go", strings.NewReader(source), "src", "1.22.0")
+			if err != nil {
+				t.Fatalf("ParseSource returned error: %v", err)
+			}
+			scopes := make(map[string]string, len(interfaces))
+			for interf, location := range interfaces {
+				scopes[interf.Name] = location.Scope
+			}
+			if !reflect.DeepEqual(scopes, test.expected) {
+				t.Errorf("found interfaces with scopes %v, expected %v", scopes, test.expected)
+			}
+		})
+	}
+}
+
+func TestDuplicateWinnerIsStable(t *testing.T) {
+	// a.go is the first file without platform suffix, whatever the order
+	// of files and workers
+	files := []sourceFile{
+		{name: "go/src/net/b.go", source: []byte("package net\n\ntype Conn interface {\n\tClose() error\n}\n")},
+		{name: "go/src/net/a.go", source: []byte("package net\n\ntype Dialer interface {\n\tDial() (Conn, error)\n}\n\ntype Conn interface {\n\tClose() error\n}\n")},
+		{name: "go/src/net/conn_linux.go", source: []byte("package net\n\ntype Conn interface {\n\tClose() error\n}\n")},
+	}
+	orders := [][]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+	for _, order := range orders {
+		for _, workers := range []int{1, len(order)} {
+			t.Run(fmt.Sprintf("%v/%d", order, workers), func(t *testing.T) {
+				extractor := &Extractor{Workers: workers}
+				interfaces, err := extractor.parseFiles("1.22.0", func(sent chan<- sourceFile) error {
+					for _, index := range order {
+						file := files[index]
+						file.tree = releaseTree("src")
+						sent <- file
+					}
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("parseFiles returned error: %v", err)
+				}
+				location := interfaces[Interface{Name: "Conn", Package: "net"}]
+				if location.SourceFile != "src/net/a.go" || location.LineNumber != "7" {
+					t.Errorf("kept %s:%s, expected src/net/a.go:7", location.SourceFile, location.LineNumber)
+				}
+			})
+		}
+	}
+}
+
+func TestSourcePackage(t *testing.T) {
+	tests := []struct {
+		filename string
+		pack     string
+		ok       bool
+	}{
+		{filename: "go/src/foo.go", pack: "", ok: true},
+		{filename: "go/src/a/b/c.go", pack: "a/b", ok: true},
+		{filename: "go/src/io/io.go", pack: "io", ok: true},
+		{filename: "go/test/foo.go", ok: false},
+	}
+	extractor := &Extractor{}
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			pack, ok := extractor.sourcePackage(test.filename, releaseTree("src"))
+			if pack != test.pack || ok != test.ok {
+				t.Errorf("sourcePackage(%q) = %q, %t, expected %q, %t", test.filename, pack, ok, test.pack, test.ok)
+			}
+		})
+	}
+}
+
+func TestReleaseTreeSlashes(t *testing.T) {
+	for _, sourceDir := range []string{"src/", "/src", "/src/"} {
+		if tree := releaseTree(sourceDir); tree != releaseTree("src") {
+			t.Errorf("tree of source dir %q is %+v, expected %+v", sourceDir, tree, releaseTree("src"))
+		}
+	}
+}
+
+func TestSortedSameNames(t *testing.T) {
+	interfaces := map[Interface]Location{
+		{Name: "Conn", Package: "net"}:                 {SourceFile: "src/net/net.go", LineNumber: "113", MethodCount: 8},
+		{Name: "Conn", Package: "database/sql/driver"}: {SourceFile: "src/database/sql/driver/driver.go", LineNumber: "245", MethodCount: 3},
+		{Name: "Conn", Package: "crypto/tls"}:          {SourceFile: "src/crypto/tls/conn.go", LineNumber: "30", MethodCount: 3},
+		{Name: "Addr", Package: "net"}:                 {SourceFile: "src/net/net.go", LineNumber: "107", MethodCount: 2},
+	}
+	tests := []struct {
+		order    string
+		expected []string
+	}{
+		{order: SortName, expected: []string{"net.Addr", "crypto/tls.Conn", "database/sql/driver.Conn", "net.Conn"}},
+		{order: SortPackage, expected: []string{"crypto/tls.Conn", "database/sql/driver.Conn", "net.Addr", "net.Conn"}},
+		{order: SortFile, expected: []string{"crypto/tls.Conn", "database/sql/driver.Conn", "net.Addr", "net.Conn"}},
+	}
+	for _, test := range tests {
+		t.Run(test.order, func(t *testing.T) {
+			// map iteration differs on each run, so several lists are sorted
+			for i := 0; i < 10; i++ {
+				list := NewInterfaceList()
+				list.AddInterfaces("1.22.0", interfaces)
+				names := make([]string, 0, len(interfaces))
+				for _, interf := range list.Sorted([]string{"1.22.0"}, test.order) {
+					names = append(names, interf.QualifiedName())
+				}
+				if !reflect.DeepEqual(names, test.expected) {
+					t.Fatalf("sorted %q, expected %q", names, test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestScanASTTypeSets(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		methods    []string
+		kind       InterfaceKind
+		constraint string
+	}{
+		{
+			name:       "union",
+			source:     "type Integer interface {\n\t~int | ~int64\n}\n",
+			methods:    []string{},
+			kind:       KindConstraint,
+			constraint: "~int | ~int64",
+		},
+		{
+			name:    "methods",
+			source:  "type ReadCloser interface {\n\tio.Reader\n\tClose() error\n}\n",
+			methods: []string{"io.Reader", "Close() error"},
+			kind:    KindMethods,
+		},
+		{
+			name:       "mixed",
+			source:     "type StringInt interface {\n\t~int\n\tfmt.Stringer\n\tString() string\n}\n",
+			methods:    []string{"fmt.Stringer", "String() string"},
+			kind:       KindMixed,
+			constraint: "~int",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := "package num\n\n" + test.source
+			declarations, err := scanAST("num.go", strings.NewReader(source), KindInterface, false, false, false, false)
+			if err != nil {
+				t.Fatalf("scanAST returned error: %v", err)
+			}
+			if len(declarations) != 1 {
+				t.Fatalf("scanAST found %d declarations, expected 1", len(declarations))
+			}
+			decl := declarations[0]
+			signatures := make([]string, 0, len(decl.methods))
+			for _, method := range decl.methods {
+				signatures = append(signatures, method.Signature)
+			}
+			if !reflect.DeepEqual(signatures, test.methods) {
+				t.Errorf("methods are %q, expected %q", signatures, test.methods)
+			}
+			if decl.kind != test.kind {
+				t.Errorf("kind is %s, expected %s", decl.kind, test.kind)
+			}
+			if decl.constraint != test.constraint {
+				t.Errorf("constraint is %q, expected %q", decl.constraint, test.constraint)
+			}
+		})
+	}
+}
+
+func TestInterfacesForVersionFromMirror(t *testing.T) {
+	extractor := &Extractor{
+		Mirror:     testserver.ServeArchives(t, "testdata"),
+		SkipVerify: true,
+		CacheDir:   t.TempDir(),
+		Workers:    2,
+	}
+	interfaces, err := extractor.InterfacesForVersion(context.Background(), "1.22.0")
+	if err != nil {
+		t.Fatalf("InterfacesForVersion returned error: %v", err)
+	}
+	expected := map[Interface]string{
+		{Name: "Reader", Package: "io"}:     "https://github.com/golang/go/blob/go1.22.0/src/io/io.go#L4",
+		{Name: "Writer", Package: "io"}:     "https://github.com/golang/go/blob/go1.22.0/src/io/io.go#L9",
+		{Name: "ReadWriter", Package: "io"}: "https://github.com/golang/go/blob/go1.22.0/src/io/io.go#L14",
+		{Name: "Conn", Package: "net"}:      "https://github.com/golang/go/blob/go1.22.0/src/net/net.go#L6",
+	}
+	links := make(map[Interface]string, len(interfaces))
+	for interf, location := range interfaces {
+		links[interf] = location.Link
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("found interfaces with links %v, expected %v", links, expected)
+	}
+	if count := interfaces[Interface{Name: "Conn", Package: "net"}].MethodCount; count != 2 {
+		t.Errorf("net.Conn has %d methods, expected 2", count)
+	}
+	// commands are skipped in releases
+	if parsed := extractor.ParsedFiles("1.22.0"); parsed != 2 {
+		t.Errorf("parsed %d files, expected 2", parsed)
+	}
+}
+
+func TestPlanCachePath(t *testing.T) {
+	cacheDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cacheDir, "go1.22.0.src.tar.gz"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		template  string
+		cachePath string
+		cached    bool
+	}{
+		{template: "", cachePath: "go1.22.0.src.tar.gz", cached: true},
+		{template: "go{{.Version}}.tar.gz", cachePath: "go1.22.0.tar.gz", cached: false},
+		{template: "{{.Version}}/go.tar.gz", cachePath: "1.22.0_go.tar.gz", cached: false},
+	}
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			extractor := &Extractor{Mirror: "http://localhost:8080/", FilenameTemplate: test.template, CacheDir: cacheDir}
+			plan, err := extractor.Plan("1.22.0")
+			if err != nil {
+				t.Fatalf("Plan returned error: %v", err)
+			}
+			if expected := filepath.Join(cacheDir, test.cachePath); plan.CachePath != expected {
+				t.Errorf("cache path is %s, expected %s", plan.CachePath, expected)
+			}
+			if plan.Cached != test.cached {
+				t.Errorf("cached is %t, expected %t", plan.Cached, test.cached)
+			}
+		})
+	}
+}
+
+func TestLocationPosition(t *testing.T) {
+	source := "package io\n\ntype Reader interface {\n\tRead(p []byte) (n int, err error)\n}\n\n" +
+		"type (\n\tWriter interface {\n\t\tWrite(p []byte) (n int, err error)\n\t}\n)\n"
+	interfaces := parse(&Extractor{}, "go/src/io/io.go", source)
+	tests := []struct {
+		name   string
+		line   string
+		column int
+		offset int
+	}{
+		{name: "Reader", line: "3", column: 6, offset: 17},
+		{name: "Writer", line: "8", column: 2, offset: 82},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location := interfaces[Interface{Name: test.name, Package: "io"}]
+			if location.LineNumber != test.line || location.Column != test.column || location.Offset != test.offset {
+				t.Errorf("%s is at line %s, column %d, offset %d, expected line %s, column %d, offset %d",
+					test.name, location.LineNumber, location.Column, location.Offset, test.line, test.column, test.offset)
+			}
+			if name := source[location.Offset : location.Offset+len(test.name)]; name != test.name {
+				t.Errorf("offset of %s points at %q", test.name, name)
+			}
+		})
+	}
+}
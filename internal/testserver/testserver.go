@@ -0,0 +1,18 @@
+// Package testserver serves source archives of testdata to tests, as a
+// mirror of GO source archives.
+package testserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ServeArchives serves source archives in directory dir, such as testdata,
+// as a mirror and returns its base URL. Server is closed at the end of test.
+func ServeArchives(t testing.TB, dir string) string {
+	t.Helper()
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(server.Close)
+	return server.URL + "/"
+}